@@ -7,6 +7,10 @@ import (
 	"io"
 	"fmt"
 	"log"
+	"net/url"
+	"errors"
+	"strconv"
+	"time"
 )
 
 /* ---------- client helpers ---------- */
@@ -151,4 +155,53 @@ func CheckDeployGuard(brokerURL, name, version, environment string) (bool, error
 	}
 
 	return respBody.Status, nil
-}
\ No newline at end of file
+}
+
+func GetContracts(brokerURL, providerName, environment string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("provider", providerName)
+	if len(environment) != 0 {
+		query.Set("environment", environment)
+	}
+
+	resp, err := http.Get(brokerURL + "/api/contracts?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = logHTTPErrorThenExit(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return bodyBytes, nil
+}
+
+/*
+CheckBrokerReachable requests the broker's participants, which every broker
+serves. Unlike the other requests, errors are returned instead of exiting, so
+that signet doctor can report them.
+*/
+func CheckBrokerReachable(brokerURL string) error {
+	httpClient := http.Client{Timeout: 5 * time.Second}
+
+	resp, err := httpClient.Get(brokerURL + "/api/participants")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return errors.New("the broker responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+
+	return nil
+}
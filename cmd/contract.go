@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+var harPath string
+var matchesPath string
+var baseURL string
+var consumerName string
+
+var contractCmd = &cobra.Command{
+	Use:   "contract",
+	Short: "work with consumer contracts",
+	Long:  `work with consumer contracts`,
+}
+
+var contractImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "generate a consumer contract from an existing traffic capture",
+	Long: `generate a consumer contract from a HAR file or a directory of mountebank matches, without re-running tests through signet proxy. Captured requests and responses are converted to interactions the same way signet proxy converts the traffic it records.
+
+	flags:
+
+	--har               the path to a HAR file (ex. exported from browser dev tools or an API gateway)
+
+	--matches           the path to a directory of recorded mountebank matches
+
+	--base-url          only import requests to URLs starting with this value (optional, only for --har)
+
+	-c --consumer       the canonical name of the consumer service
+
+	-m --provider       the canonical name of the provider service
+
+	-p --path           the relative path and filename that the consumer contract will be written to
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		harPath = viper.GetString("contract.import.har")
+		matchesPath = viper.GetString("contract.import.matches")
+		baseURL = viper.GetString("contract.import.base-url")
+		consumerName = viper.GetString("contract.import.consumer")
+		providerName = viper.GetString("contract.import.provider")
+		path = viper.GetString("contract.import.path")
+
+		err := validateContractImportFlags(harPath, matchesPath, consumerName, providerName, path)
+		if err != nil {
+			return err
+		}
+
+		var exchanges []utils.Exchange
+		var skipped []error
+		source := harPath
+		if len(harPath) != 0 {
+			exchanges, err = utils.LoadHARExchanges(harPath, baseURL)
+		} else {
+			source = matchesPath
+			exchanges, skipped, err = utils.LoadMatchesExchanges(matchesPath)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, skippedErr := range skipped {
+			cmd.Println(colorRed + "Warning" + colorReset + " - " + skippedErr.Error())
+		}
+
+		count, err := utils.CreatePactFromExchanges(exchanges, path, consumerName, providerName)
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			cmd.Println("Info - No contract was generated because " + source + " does not contain any requests to import")
+			return nil
+		}
+
+		cmd.Println(colorGreen + "Imported" + colorReset + " - wrote a consumer contract with " + strconv.Itoa(count) + " interactions from " + source + " to " + path)
+
+		return nil
+	},
+}
+
+func validateContractImportFlags(harPath, matchesPath, consumerName, providerName, path string) error {
+	if len(harPath) == 0 && len(matchesPath) == 0 {
+		return errors.New("No --har or --matches was provided. One of these flags is required.")
+	}
+
+	if len(harPath) != 0 && len(matchesPath) != 0 {
+		return errors.New("--har and --matches cannot be used together.")
+	}
+
+	if len(consumerName) == 0 {
+		return errors.New("No --consumer was provided. This is a required flag.")
+	}
+
+	if len(providerName) == 0 {
+		return errors.New("No --provider was provided. This is a required flag.")
+	}
+
+	if len(path) == 0 {
+		return errors.New("No --path was provided. This is a required flag.")
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(contractCmd)
+	contractCmd.AddCommand(contractImportCmd)
+
+	contractImportCmd.Flags().StringVar(&harPath, "har", "", "the path to a HAR file")
+	contractImportCmd.Flags().StringVar(&matchesPath, "matches", "", "the path to a directory of recorded mountebank matches")
+	contractImportCmd.Flags().StringVar(&baseURL, "base-url", "", "only import requests to URLs starting with this value (optional, only for --har)")
+	contractImportCmd.Flags().StringVarP(&consumerName, "consumer", "c", "", "the canonical name of the consumer service")
+	contractImportCmd.Flags().StringVarP(&providerName, "provider", "m", "", "the canonical name of the provider service")
+	contractImportCmd.Flags().StringVarP(&path, "path", "p", "", "the relative path and filename that the consumer contract will be written to")
+
+	viper.BindPFlag("contract.import.har", contractImportCmd.Flags().Lookup("har"))
+	viper.BindPFlag("contract.import.matches", contractImportCmd.Flags().Lookup("matches"))
+	viper.BindPFlag("contract.import.base-url", contractImportCmd.Flags().Lookup("base-url"))
+	viper.BindPFlag("contract.import.consumer", contractImportCmd.Flags().Lookup("consumer"))
+	viper.BindPFlag("contract.import.provider", contractImportCmd.Flags().Lookup("provider"))
+	viper.BindPFlag("contract.import.path", contractImportCmd.Flags().Lookup("path"))
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+/* ------------- helpers ------------- */
+
+func callContractImport(argsAndFlags []string) actualOut {
+	actual := new(bytes.Buffer)
+	RootCmd.SetOut(actual)
+	RootCmd.SetErr(actual)
+	RootCmd.SetArgs(append([]string{"contract", "import"}, argsAndFlags...))
+	RootCmd.Execute()
+	return actualOut{actual.String()}
+}
+
+/* ------------- tests ------------- */
+
+func TestContractImportNoSource(t *testing.T) {
+	flags := []string{
+		"--consumer", "service_1",
+		"--provider", "user_service",
+		"--path", "./contracts/cons-prov.json",
+	}
+	actual := callContractImport(flags)
+	expected := "Error: No --har or --matches was provided."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestContractImportNoConsumer(t *testing.T) {
+	flags := []string{
+		"--har", "../data_test/session.har",
+		"--provider", "user_service",
+		"--path", "./contracts/cons-prov.json",
+	}
+	actual := callContractImport(flags)
+	expected := "Error: No --consumer was provided."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestContractImportHAR(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+	flags := []string{
+		"--har", "../data_test/session.har",
+		"--base-url", "http://localhost:3002",
+		"--consumer", "service_1",
+		"--provider", "user_service",
+		"--path", pactPath,
+	}
+	callContractImport(flags)
+	interactions := contractInteractions(t, pactPath)
+
+	t.Run("skips duplicates, other hosts, and entries without a response", func(t *testing.T) {
+		if len(interactions) != 3 {
+			t.Fatalf("expected 3 interactions, got %d", len(interactions))
+		}
+	})
+
+	t.Run("only keeps contract headers", func(t *testing.T) {
+		requestHeaders := interactions[0]["request"].(map[string]interface{})["headers"].(map[string]interface{})
+		responseHeaders := interactions[0]["response"].(map[string]interface{})["headers"].(map[string]interface{})
+
+		if len(requestHeaders) != 1 || requestHeaders["Accept"] != "application/json" {
+			t.Error(requestHeaders)
+		}
+
+		if len(responseHeaders) != 1 || responseHeaders["Content-Type"] == nil {
+			t.Error(responseHeaders)
+		}
+	})
+
+	t.Run("records repeated query parameters as a list", func(t *testing.T) {
+		query := interactions[1]["request"].(map[string]interface{})["query"].(map[string]interface{})
+
+		if query["active"] != "true" || len(query["role"].([]interface{})) != 2 {
+			t.Error(query)
+		}
+	})
+
+	t.Run("uses the request body content type", func(t *testing.T) {
+		request := interactions[2]["request"].(map[string]interface{})
+
+		if interactions[2]["description"] != "POST /users 201" || request["headers"].(map[string]interface{})["Content-Type"] != "application/json" {
+			t.Error(interactions[2])
+		}
+	})
+
+	teardown()
+}
+
+func TestContractImportMatches(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+	flags := []string{
+		"--matches", "../data_test/stubs",
+		"--consumer", "service_1",
+		"--provider", "user_service",
+		"--path", pactPath,
+	}
+	callContractImport(flags)
+	interactions := contractInteractions(t, pactPath)
+
+	if len(interactions) != 2 {
+		t.Errorf("expected 2 interactions, got %d", len(interactions))
+	}
+
+	teardown()
+}
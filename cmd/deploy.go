@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"context"
+	_ "embed"
 	"io/ioutil"
-	"time"
+	"regexp"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
@@ -15,12 +17,86 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 )
 
+// stacks created by deploy are tagged, so that deploy list can find them
+const stackTagKey = "signet-framework"
+const stackTagValue = "broker"
+
+var stackName string
+var templatePath string
+
+// the template is embedded so that the binary can deploy the broker without the npm package
+//go:embed templates/cftemplate.yaml
+var embeddedTemplate string
+
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy the Signet broker to a new ECS Fargate cluster",
-	Long:  `Deploy the Signet broker to a new ECS Fargate cluster`,
+	Long:  `Deploy the Signet broker to a new ECS Fargate cluster
+
+	flags:
+
+	--stack-name        the name of the CloudFormation stack, so that several brokers (ex. staging and production) can be deployed to one AWS account and region (optional, defaults to signetbroker)
+
+	--template          path to a custom CloudFormation template to deploy instead of the built in template (optional, see signet deploy template)
+
+	--parameters        path to a JSON or YAML file of template parameters, either a map of parameter names to values or the list of ParameterKey and ParameterValue objects used by the AWS CLI (optional)
+
+	--vpc-id            an existing VPC to deploy into, instead of creating a new VPC (optional, requires --load-balancer-subnets and --service-subnets)
+
+	--load-balancer-subnets  comma separated subnets of --vpc-id for the load balancer, in at least two availability zones (optional)
+
+	--service-subnets   comma separated subnets of --vpc-id for the broker tasks and the database, ex. private subnets with a NAT gateway (optional)
+
+	--task-cpu          the CPU units of each broker task: 256, 512, 1024, 2048, or 4096 (optional, defaults to 512)
+
+	--task-memory       the memory of each broker task in MiB, which must be valid for --task-cpu on Fargate (optional, defaults to 1024)
+
+	--desired-count     the number of broker tasks to run, from 1 to 10 (optional, defaults to 1)
+
+	--db-instance-class the RDS instance class of the database (optional, defaults to db.t3.micro)
+
+	--db-storage        the storage of the database in GiB, at least 20 (optional, defaults to 20)
+
+	--db-backup-retention  the number of days database backups are kept, from 0 to 35 (optional, defaults to 7)
+
+	--log-retention     the number of days broker logs are kept, one of the CloudWatch Logs retention periods (optional, defaults to 30)
+
+	--image             the container image of the broker, ex. signetframework/signet-broker:1.2.0 (optional, defaults to signetframework/signet-broker:latest)
+
+	--upgrade           upgrade the existing stack instead of creating it: the planned changes are shown as a change set, and executed once confirmed (optional)
+
+	-y --yes            execute the change set of --upgrade without asking for confirmation, ex. in CI (optional)
+
+	--dry-run           validate the template and parameters, and show the resources that would be created (or with --upgrade, the planned changes) without deploying anything (optional)
+
+	--timeout           how long to wait for the stack to be created or upgraded, ex. 45m (optional, defaults to 30m)
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+
+	parameter flags override the values in --parameters, and every parameter is checked against the template before the stack is created. A custom --template must declare the parameters of the flags it is deployed with. With --upgrade, parameters that are not given keep the value the stack was deployed with
+`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		template, err := getCloudFormationTemplate()
+		stackName = viper.GetString("deploy.stack-name")
+		err := validateStackName(stackName)
+		if err != nil {
+			return err
+		}
+
+		upgrade = viper.GetBool("deploy.upgrade")
+		assumeYes = viper.GetBool("deploy.yes")
+		if assumeYes && !upgrade {
+			return errors.New("--yes can only be used with --upgrade")
+		}
+
+		deployTimeout = viper.GetDuration("deploy.timeout")
+		err = validateTimeout(deployTimeout)
+		if err != nil {
+			return err
+		}
+
+		templatePath = viper.GetString("deploy.template")
+		template, err := getCloudFormationTemplate(templatePath)
 		if err != nil {
 			return err
 		}
@@ -29,28 +105,57 @@ var deployCmd = &cobra.Command{
 		if err != nil {
 			return errors.New("unable to load SDK config - have you configured your aws cli with `aws configure sso`? Have you logged in with `aws login sso`?" + err.Error())
 		}
+
+		parametersPath = viper.GetString("deploy.parameters")
+		params, err := getStackParameters(parametersPath, cfg.Region)
+		if err != nil {
+			return err
+		}
+
+		cfClient := cloudformation.NewFromConfig(cfg)
+
+		dryRun = viper.GetBool("deploy.dry-run")
+		if dryRun {
+			err = validateTemplate(cfClient, template)
+			if err != nil {
+				return err
+			}
+		}
+
+		if upgrade {
+			return upgradeStack(cmd, cfClient, template, params)
+		}
+		if dryRun {
+			return previewDeploy(cmd, cfClient, template, params)
+		}
+
+		err = validateStackParameters(params, template)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
 		
 		csInput := &cloudformation.CreateStackInput{
 			StackName: aws.String(stackName),
 			TemplateBody: aws.String(template),
 			Capabilities: []types.Capability{"CAPABILITY_IAM"},
-			Parameters: []types.Parameter{
+			Tags: []types.Tag{
 				{
-					ParameterKey: aws.String("Region"),
-					ParameterValue: aws.String(cfg.Region),
+					Key: aws.String(stackTagKey),
+					Value: aws.String(stackTagValue),
 				},
 			},
+			Parameters: toCloudFormationParameters(params),
 		}
 
-		cfClient := cloudformation.NewFromConfig(cfg)
-		_, err = cfClient.CreateStack(context.TODO(), csInput)
+		csOutput, err := cfClient.CreateStack(context.TODO(), csInput)
 		if err != nil {
 			return errors.New("unable to create CloudFormation stack - try checking your CloudFormation Events log for details about the failure: " + err.Error())
 		}
 
-		fmt.Println(colorGreen + "Deploying" + colorReset + " - deploying the Signet broker to a new ECS Fargate cluster, this will take a few minutes...")
+		fmt.Println(colorGreen + "Deploying" + colorReset + " - deploying the Signet broker to a new ECS Fargate cluster in stack " + stackName + ", this will take a few minutes...")
 
-		if err := waitForDeploymentDone(cfClient); err != nil {
+		if err := waitForDeploymentDone(cfClient, aws.ToString(csOutput.StackId)); err != nil {
 			return err
 		}
 
@@ -62,13 +167,25 @@ var deployCmd = &cobra.Command{
 	},
 }
 
-func getCloudFormationTemplate() (string, error) {
-	signetRoot, err := getNpmPkgRoot()
-	if err != nil {
-		return "", errors.New("unable to find signet-cli global npm package: " + err.Error())
+var stackNamePattern = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]{0,127}$`)
+
+func validateStackName(stackName string) error {
+	if !stackNamePattern.MatchString(stackName) {
+		return errors.New("--stack-name must start with a letter, and only contain letters, numbers, and dashes (at most 128 characters)")
 	}
-	templatePath := signetRoot + "/cftemplate.yaml"
-	
+	return nil
+}
+
+/*
+getCloudFormationTemplate returns the template at templatePath, or the embedded
+template if no path is given. Custom templates must keep a load balancer with
+the logical ID LoadBalancer, which is used to find the URL of the broker.
+*/
+func getCloudFormationTemplate(templatePath string) (string, error) {
+	if len(templatePath) == 0 {
+		return embeddedTemplate, nil
+	}
+
 	templateFile, err := ioutil.ReadFile(templatePath)
 	if err != nil {
 		return "", errors.New("unable to load CloudFormation template: " + err.Error())
@@ -77,54 +194,73 @@ func getCloudFormationTemplate() (string, error) {
 	return string(templateFile), nil
 }
 
-func waitForDeploymentDone(cfClient *cloudformation.Client) error {
-	waiter := cloudformation.NewStackCreateCompleteWaiter(cfClient)
-	waitDuration, err := time.ParseDuration("15m")
+func waitForDeploymentDone(cfClient *cloudformation.Client, stackID string) error {
+	tracker := newStackEventTracker(stackID)
+	stack, err := watchStack(cfClient, tracker, deployTimeout)
 	if err != nil {
 		return err
 	}
 
-	name := stackName
-	dsInput := &cloudformation.DescribeStacksInput{StackName: &name}
-	if err := waiter.Wait(context.TODO(), dsInput, waitDuration); err != nil { // changed this from WaitForOutput before committing - untested
-		return errors.New("error while waiting for CloudFormation stack to be created - Check your AWS console for the status of the deployment: " + err.Error())
+	if stack.StackStatus != types.StackStatusCreateComplete {
+		return errors.New(describeStackFailure("deployment", stack, tracker) + " - delete the stack with `signet undeploy --confirm " + stackName + "` before deploying it again")
 	}
 
 	return nil
 }
 
 func printURLofELB(cfClient *cloudformation.Client, cfg aws.Config) error {
-	name := stackName
-	dsrInput := &cloudformation.DescribeStackResourcesInput{StackName: &name}
-
-	dsrOutput, err := cfClient.DescribeStackResources(context.TODO(), dsrInput)
+	brokerURL, err := getBrokerURLofStack(cfClient, cfg, stackName)
 	if err != nil {
 		fmt.Println("Cannot display the URL of the ELB in front of the Signet broker cluster - check AWS console for the ELB's URL")
 		return nil
 	}
 
+	fmt.Println("Signet broker is exposed through an Elastic Load Balancer at " + colorBlue + brokerURL + colorReset)
+	fmt.Println("\nAdd a TLS certificate to the ELB to enable HTTPS")
+	fmt.Println("Run `signet deploy status --write-config` to save it as broker-url in " + configFileName)
+
+	return nil
+}
+
+// the broker is served by the load balancer with the logical ID LoadBalancer in the template
+func getBrokerURLofStack(cfClient *cloudformation.Client, cfg aws.Config, stackName string) (string, error) {
+	dsrInput := &cloudformation.DescribeStackResourcesInput{StackName: aws.String(stackName)}
+
+	dsrOutput, err := cfClient.DescribeStackResources(context.TODO(), dsrInput)
+	if err != nil {
+		return "", err
+	}
+
 	var elbArn string
 	for _, resource := range dsrOutput.StackResources {
-		if *resource.LogicalResourceId == "LoadBalancer" {
+		if *resource.LogicalResourceId == "LoadBalancer" && resource.PhysicalResourceId != nil {
 			elbArn = *resource.PhysicalResourceId
 		}
 	}
+	if len(elbArn) == 0 {
+		return "", errors.New("stack " + stackName + " has no load balancer")
+	}
 
 	elbClient := elasticloadbalancingv2.NewFromConfig(cfg)
 	dlbInput := elasticloadbalancingv2.DescribeLoadBalancersInput{LoadBalancerArns: []string{elbArn}}
 	dlbOutput, err := elbClient.DescribeLoadBalancers(context.TODO(), &dlbInput)
 	if err != nil {
-		fmt.Println("Cannot display the URL of the ELB in front of the Signet broker cluster - check AWS console for the ELB's URL")
-		return nil
+		return "", err
+	}
+	if len(dlbOutput.LoadBalancers) == 0 {
+		return "", errors.New("the load balancer of stack " + stackName + " was not found")
 	}
 
-	dnsName := *dlbOutput.LoadBalancers[0].DNSName
-	fmt.Println("Signet broker is exposed through an Elastic Load Balancer at " + colorBlue + "http://" + dnsName + colorReset)
-	fmt.Println("\nAdd a TLS certificate to the ELB to enable HTTPS")
-
-	return nil
+	return "http://" + *dlbOutput.LoadBalancers[0].DNSName, nil
 }
 	
 func init() {
 	RootCmd.AddCommand(deployCmd)
+
+	deployCmd.PersistentFlags().StringVar(&stackName, "stack-name", defaultStackName, "the name of the CloudFormation stack (optional)")
+
+	deployCmd.Flags().StringVar(&templatePath, "template", "", "path to a custom CloudFormation template to deploy instead of the built in template (optional)")
+
+	viper.BindPFlag("deploy.stack-name", deployCmd.PersistentFlags().Lookup("stack-name"))
+	viper.BindPFlag("deploy.template", deployCmd.Flags().Lookup("template"))
 }
\ No newline at end of file
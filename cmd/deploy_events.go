@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/viper"
+)
+
+const defaultStackTimeout = 30 * time.Minute
+
+var deployTimeout time.Duration
+
+// separate from deployTimeout, so that each command keeps its own default
+var undeployTimeout time.Duration
+
+// how often the events of a stack are read while it is being changed
+const stackPollInterval = 5 * time.Second
+
+/*
+stackEventTracker follows the events of a stack operation, remembering the
+latest status of each resource and the first resource that failed. The stack's
+own events are printed, but are not counted as resources.
+*/
+type stackEventTracker struct {
+	stackID      string
+	seen         map[string]bool
+	statuses     map[string]types.ResourceStatus
+	firstFailure *types.StackEvent
+}
+
+func newStackEventTracker(stackID string) *stackEventTracker {
+	return &stackEventTracker{
+		stackID:  stackID,
+		seen:     map[string]bool{},
+		statuses: map[string]types.ResourceStatus{},
+	}
+}
+
+/*
+startStackEventTracker marks the latest events of an existing stack as seen
+before it is changed, so that only the events of the new operation are shown.
+Event IDs are used rather than timestamps, which depend on the local clock.
+*/
+func startStackEventTracker(cfClient *cloudformation.Client, stackID string) (*stackEventTracker, error) {
+	tracker := newStackEventTracker(stackID)
+
+	deInput := &cloudformation.DescribeStackEventsInput{StackName: aws.String(stackID)}
+	deOutput, err := cfClient.DescribeStackEvents(context.TODO(), deInput)
+	if err != nil {
+		return nil, errors.New("unable to read the events of stack " + stackName + ": " + err.Error())
+	}
+
+	for _, event := range deOutput.StackEvents {
+		tracker.seen[aws.ToString(event.EventId)] = true
+	}
+
+	return tracker, nil
+}
+
+// add records the events that have not been seen yet, and returns them oldest first
+func (t *stackEventTracker) add(events []types.StackEvent) []types.StackEvent {
+	newEvents := []types.StackEvent{}
+	for _, event := range events {
+		eventID := aws.ToString(event.EventId)
+		if t.seen[eventID] || event.Timestamp == nil {
+			continue
+		}
+		t.seen[eventID] = true
+		newEvents = append(newEvents, event)
+	}
+
+	sort.SliceStable(newEvents, func(i, j int) bool {
+		return newEvents[i].Timestamp.Before(*newEvents[j].Timestamp)
+	})
+
+	for i, event := range newEvents {
+		if aws.ToString(event.PhysicalResourceId) == t.stackID || aws.ToString(event.ResourceType) == "AWS::CloudFormation::Stack" {
+			continue
+		}
+
+		t.statuses[aws.ToString(event.LogicalResourceId)] = event.ResourceStatus
+
+		if t.firstFailure == nil && isFailureEvent(event) {
+			t.firstFailure = &newEvents[i]
+		}
+	}
+
+	return newEvents
+}
+
+// resources that CloudFormation cancels because another resource failed are not the cause of the failure
+func isFailureEvent(event types.StackEvent) bool {
+	reason := aws.ToString(event.ResourceStatusReason)
+	return strings.HasSuffix(string(event.ResourceStatus), "_FAILED") && !strings.Contains(reason, "cancelled")
+}
+
+func (t *stackEventTracker) summary() string {
+	complete, inProgress, failed := 0, 0, 0
+	for _, status := range t.statuses {
+		switch {
+		case strings.HasSuffix(string(status), "_COMPLETE") || status == types.ResourceStatusDeleteSkipped:
+			complete++
+		case strings.HasSuffix(string(status), "_IN_PROGRESS"):
+			inProgress++
+		case strings.HasSuffix(string(status), "_FAILED"):
+			failed++
+		}
+	}
+
+	summary := strconv.Itoa(complete) + " of " + strconv.Itoa(len(t.statuses)) + " resources complete"
+	if inProgress != 0 {
+		summary += ", " + strconv.Itoa(inProgress) + " in progress"
+	}
+	if failed != 0 {
+		summary += ", " + strconv.Itoa(failed) + " failed"
+	}
+	return summary
+}
+
+// failureReason describes the first resource that failed, which is usually the cause of a rollback
+func (t *stackEventTracker) failureReason() string {
+	if t.firstFailure == nil {
+		return ""
+	}
+
+	event := t.firstFailure
+	return aws.ToString(event.LogicalResourceId) + " (" + aws.ToString(event.ResourceType) + ") " + string(event.ResourceStatus) + ": " + aws.ToString(event.ResourceStatusReason)
+}
+
+func describeStackFailure(operation string, stack types.Stack, tracker *stackEventTracker) string {
+	message := "the " + operation + " of stack " + stackName + " failed with status " + string(stack.StackStatus)
+	if reason := tracker.failureReason(); len(reason) != 0 {
+		message += ", because " + reason
+	}
+	return message
+}
+
+func formatStackEvent(event types.StackEvent) string {
+	status := string(event.ResourceStatus)
+
+	color := colorGreen
+	if strings.HasSuffix(status, "_IN_PROGRESS") {
+		color = colorBlue
+	} else if strings.Contains(status, "FAILED") || strings.Contains(status, "ROLLBACK") {
+		color = colorRed
+	}
+
+	line := event.Timestamp.Local().Format("15:04:05") + "  " + color + status + colorReset + "  " + aws.ToString(event.LogicalResourceId) + " (" + aws.ToString(event.ResourceType) + ")"
+	if reason := aws.ToString(event.ResourceStatusReason); len(reason) != 0 {
+		line += " - " + reason
+	}
+
+	return line
+}
+
+/*
+watchStack prints the events of a stack operation as they happen, with a
+progress summary whenever it changes, until the stack is no longer in
+progress. The stack is looked up by ID, which works after it is deleted.
+*/
+func watchStack(cfClient *cloudformation.Client, tracker *stackEventTracker, timeout time.Duration) (types.Stack, error) {
+	stackID := tracker.stackID
+	deadline := time.Now().Add(timeout)
+	lastSummary := ""
+
+	for {
+		events, err := newStackEvents(cfClient, stackID, tracker)
+		if err != nil {
+			return types.Stack{}, err
+		}
+
+		for _, event := range tracker.add(events) {
+			fmt.Println(formatStackEvent(event))
+		}
+
+		if summary := tracker.summary(); summary != lastSummary && len(tracker.statuses) != 0 {
+			fmt.Println("  " + summary)
+			lastSummary = summary
+		}
+
+		dsOutput, err := cfClient.DescribeStacks(context.TODO(), &cloudformation.DescribeStacksInput{StackName: aws.String(stackID)})
+		if err != nil {
+			return types.Stack{}, errors.New("unable to describe CloudFormation stack " + stackName + ": " + err.Error())
+		}
+		if len(dsOutput.Stacks) == 0 {
+			return types.Stack{}, errors.New("stack " + stackName + " was not found")
+		}
+
+		stack := dsOutput.Stacks[0]
+		if !strings.HasSuffix(string(stack.StackStatus), "_IN_PROGRESS") {
+			// the final events can be published after the stack's status changes
+			events, err := newStackEvents(cfClient, stackID, tracker)
+			if err == nil {
+				for _, event := range tracker.add(events) {
+					fmt.Println(formatStackEvent(event))
+				}
+			}
+			return stack, nil
+		}
+
+		if time.Now().After(deadline) {
+			return stack, errors.New("timed out after " + timeout.String() + " waiting for stack " + stackName + ", whose status is " + string(stack.StackStatus) + " - CloudFormation is still working on it, check on it with `signet deploy status`, or wait longer with --timeout")
+		}
+
+		time.Sleep(stackPollInterval)
+	}
+}
+
+// newStackEvents reads events newest first, stopping at the first page that reaches events already seen
+func newStackEvents(cfClient *cloudformation.Client, stackID string, tracker *stackEventTracker) ([]types.StackEvent, error) {
+	events := []types.StackEvent{}
+
+	paginator := cloudformation.NewDescribeStackEventsPaginator(cfClient, &cloudformation.DescribeStackEventsInput{StackName: aws.String(stackID)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, errors.New("unable to read the events of stack " + stackName + ": " + err.Error())
+		}
+
+		events = append(events, page.StackEvents...)
+
+		for _, event := range page.StackEvents {
+			if tracker.seen[aws.ToString(event.EventId)] {
+				return events, nil
+			}
+		}
+	}
+
+	return events, nil
+}
+
+func validateTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return errors.New("--timeout must be a positive duration, ex. 30m")
+	}
+	return nil
+}
+
+func init() {
+	deployCmd.Flags().DurationVar(&deployTimeout, "timeout", defaultStackTimeout, "how long to wait for the stack to be created or upgraded (optional)")
+	undeployCmd.Flags().DurationVar(&undeployTimeout, "timeout", defaultStackTimeout, "how long to wait for the stack to be deleted (optional)")
+
+	viper.BindPFlag("deploy.timeout", deployCmd.Flags().Lookup("timeout"))
+	viper.BindPFlag("undeploy.timeout", undeployCmd.Flags().Lookup("timeout"))
+}
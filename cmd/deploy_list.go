@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+)
+
+var deployListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the Signet brokers deployed to the AWS account and region",
+	Long: `List the Signet brokers deployed to the AWS account and region, with the status of their CloudFormation stacks
+
+	flags:
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadDefaultConfig(context.TODO())
+		if err != nil {
+			return errors.New("unable to load SDK config - have you configured your aws cli with `aws configure sso`? Have you logged in with `aws login sso`?" + err.Error())
+		}
+
+		cfClient := cloudformation.NewFromConfig(cfg)
+		stacks, err := listBrokerStacks(cfClient)
+		if err != nil {
+			return errors.New("unable to list CloudFormation stacks: " + err.Error())
+		}
+
+		if len(stacks) == 0 {
+			fmt.Println("No Signet brokers are deployed in " + cfg.Region)
+			return nil
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "STACK NAME\tSTATUS\tCREATED")
+		for _, stack := range stacks {
+			fmt.Fprintln(writer, *stack.StackName+"\t"+string(stack.StackStatus)+"\t"+stack.CreationTime.Format(time.RFC3339))
+		}
+		writer.Flush()
+
+		return nil
+	},
+}
+
+func listBrokerStacks(cfClient *cloudformation.Client) ([]types.Stack, error) {
+	stacks := []types.Stack{}
+
+	paginator := cloudformation.NewDescribeStacksPaginator(cfClient, &cloudformation.DescribeStacksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stack := range page.Stacks {
+			if isBrokerStack(stack) {
+				stacks = append(stacks, stack)
+			}
+		}
+	}
+
+	return stacks, nil
+}
+
+// stacks deployed before they were tagged are found by the default stack name
+func isBrokerStack(stack types.Stack) bool {
+	for _, tag := range stack.Tags {
+		if tag.Key != nil && tag.Value != nil && *tag.Key == stackTagKey && *tag.Value == stackTagValue {
+			return true
+		}
+	}
+
+	return stack.StackName != nil && *stack.StackName == defaultStackName
+}
+
+func init() {
+	deployCmd.AddCommand(deployListCmd)
+}
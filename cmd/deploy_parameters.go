@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/viper"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+var parametersPath string
+var vpcID string
+var loadBalancerSubnets []string
+var serviceSubnets []string
+var taskCPU string
+var taskMemory string
+var desiredCount string
+var dbInstanceClass string
+var dbStorage string
+var dbBackupRetention string
+var logRetention string
+var brokerImage string
+
+// each deploy flag sets a parameter of the template, overriding the parameters file
+var stackParameterFlags = []struct {
+	flag      string
+	parameter string
+}{
+	{"vpc-id", "VpcId"},
+	{"load-balancer-subnets", "LoadBalancerSubnetIds"},
+	{"service-subnets", "ServiceSubnetIds"},
+	{"task-cpu", "TaskCpu"},
+	{"task-memory", "TaskMemory"},
+	{"desired-count", "DesiredCount"},
+	{"db-instance-class", "DatabaseInstanceClass"},
+	{"db-storage", "DatabaseAllocatedStorage"},
+	{"db-backup-retention", "DatabaseBackupRetentionDays"},
+	{"log-retention", "LogRetentionDays"},
+	{"image", "BrokerImage"},
+}
+
+/*
+getStackParameters combines the parameters file with the parameter flags, and
+sets Region to the region of the AWS config unless the file sets it.
+*/
+func getStackParameters(parametersPath, region string) (map[string]string, error) {
+	params := map[string]string{}
+
+	if len(parametersPath) != 0 {
+		fileParams, err := utils.LoadStackParametersFile(parametersPath)
+		if err != nil {
+			return nil, err
+		}
+		params = fileParams
+	}
+
+	for _, mapping := range stackParameterFlags {
+		value := strings.Join(viper.GetStringSlice("deploy."+mapping.flag), ",")
+		if len(value) != 0 {
+			params[mapping.parameter] = value
+		}
+	}
+
+	if _, ok := params["Region"]; !ok {
+		params["Region"] = region
+	}
+
+	return params, nil
+}
+
+/*
+validateStackParameters checks the parameters against the template before the
+stack is created, so that a mistake fails in seconds rather than part way
+through a deployment.
+*/
+func validateStackParameters(params map[string]string, template string) error {
+	templateParams, err := utils.LoadTemplateParameters(template)
+	if err != nil {
+		return err
+	}
+
+	// a parameter set by a flag names the flag, since the template has to be changed rather than the flag
+	problems := []string{}
+	undeclared := map[string]bool{}
+	for _, mapping := range stackParameterFlags {
+		if _, ok := params[mapping.parameter]; !ok {
+			continue
+		}
+		if _, ok := templateParams[mapping.parameter]; !ok {
+			problems = append(problems, "--"+mapping.flag+" sets the "+mapping.parameter+" parameter, which the template does not declare - deploy a template that declares it with --template")
+			undeclared[mapping.parameter] = true
+		}
+	}
+
+	checkedParams := map[string]string{}
+	for name, value := range params {
+		if !undeclared[name] {
+			checkedParams[name] = value
+		}
+	}
+
+	problems = append(problems, utils.ValidateStackParameters(checkedParams, templateParams)...)
+	problems = append(problems, validateNetworkParameters(params, templateParams)...)
+
+	cpu := stackParameterValue("TaskCpu", params, templateParams)
+	memory := stackParameterValue("TaskMemory", params, templateParams)
+	if err := utils.ValidateFargateSize(cpu, memory); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) != 0 {
+		return errors.New("invalid stack parameters:\n  " + strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// an existing VPC needs subnets for the load balancer and the service, and subnets need a VPC
+func validateNetworkParameters(params map[string]string, templateParams map[string]utils.TemplateParameter) []string {
+	if _, ok := templateParams["VpcId"]; !ok {
+		return []string{}
+	}
+
+	problems := []string{}
+	vpc := stackParameterValue("VpcId", params, templateParams)
+
+	for _, parameter := range []string{"LoadBalancerSubnetIds", "ServiceSubnetIds"} {
+		subnets := []string{}
+		for _, subnet := range strings.Split(stackParameterValue(parameter, params, templateParams), ",") {
+			if subnet = strings.TrimSpace(subnet); len(subnet) != 0 {
+				subnets = append(subnets, subnet)
+			}
+		}
+
+		for _, subnet := range subnets {
+			if !strings.HasPrefix(subnet, "subnet-") {
+				problems = append(problems, parameter+" must be subnet IDs, got \""+subnet+"\"")
+			}
+		}
+
+		if len(vpc) == 0 && len(subnets) != 0 {
+			problems = append(problems, parameter+" can only be set with VpcId")
+		}
+		if len(vpc) != 0 && len(subnets) < 2 {
+			problems = append(problems, parameter+" must have subnets in at least two availability zones when VpcId is set")
+		}
+	}
+
+	return problems
+}
+
+func stackParameterValue(name string, params map[string]string, templateParams map[string]utils.TemplateParameter) string {
+	if value, ok := params[name]; ok {
+		return value
+	}
+	return strings.TrimSpace(templateParams[name].DefaultString())
+}
+
+func toCloudFormationParameters(params map[string]string) []types.Parameter {
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cfParams := []types.Parameter{}
+	for _, name := range names {
+		cfParams = append(cfParams, types.Parameter{
+			ParameterKey:   aws.String(name),
+			ParameterValue: aws.String(params[name]),
+		})
+	}
+
+	return cfParams
+}
+
+func init() {
+	deployCmd.Flags().StringVar(&parametersPath, "parameters", "", "path to a JSON or YAML file of template parameters (optional)")
+	deployCmd.Flags().StringVar(&vpcID, "vpc-id", "", "an existing VPC to deploy into (optional)")
+	deployCmd.Flags().StringSliceVar(&loadBalancerSubnets, "load-balancer-subnets", nil, "subnets of --vpc-id for the load balancer (optional)")
+	deployCmd.Flags().StringSliceVar(&serviceSubnets, "service-subnets", nil, "subnets of --vpc-id for the broker tasks and database (optional)")
+	deployCmd.Flags().StringVar(&taskCPU, "task-cpu", "", "the CPU units of each broker task (optional)")
+	deployCmd.Flags().StringVar(&taskMemory, "task-memory", "", "the memory of each broker task in MiB (optional)")
+	deployCmd.Flags().StringVar(&desiredCount, "desired-count", "", "the number of broker tasks to run (optional)")
+	deployCmd.Flags().StringVar(&dbInstanceClass, "db-instance-class", "", "the RDS instance class of the database (optional)")
+	deployCmd.Flags().StringVar(&dbStorage, "db-storage", "", "the storage of the database in GiB (optional)")
+	deployCmd.Flags().StringVar(&dbBackupRetention, "db-backup-retention", "", "the number of days database backups are kept (optional)")
+	deployCmd.Flags().StringVar(&logRetention, "log-retention", "", "the number of days broker logs are kept (optional)")
+	deployCmd.Flags().StringVar(&brokerImage, "image", "", "the container image of the broker (optional)")
+
+	viper.BindPFlag("deploy.parameters", deployCmd.Flags().Lookup("parameters"))
+	for _, mapping := range stackParameterFlags {
+		viper.BindPFlag("deploy."+mapping.flag, deployCmd.Flags().Lookup(mapping.flag))
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var dryRun bool
+
+// separate from dryRun, so that each command keeps its own default
+var undeployDryRun bool
+
+// not bound to viper, so that a config file cannot confirm every undeploy in advance
+var undeployConfirm string
+
+// resources whose data is permanently lost when they are deleted or replaced
+var dataResourceTypes = map[string]string{
+	"AWS::RDS::DBInstance":        "the broker's database of contracts, specs, and verification results",
+	"AWS::RDS::DBCluster":         "the broker's database of contracts, specs, and verification results",
+	"AWS::SecretsManager::Secret": "the database credentials",
+	"AWS::Logs::LogGroup":         "the broker's logs",
+	"AWS::S3::Bucket":             "the objects in the bucket",
+	"AWS::DynamoDB::Table":        "the items in the table",
+	"AWS::EFS::FileSystem":        "the files in the file system",
+}
+
+/*
+previewDeploy shows the resources deploy would create, by creating a change set
+for a new stack and deleting it again. CloudFormation keeps a new stack in
+REVIEW_IN_PROGRESS while its change set is reviewed, so the empty stack is
+deleted along with it.
+*/
+func previewDeploy(cmd *cobra.Command, cfClient *cloudformation.Client, template string, params map[string]string) error {
+	err := validateStackParameters(params, template)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	_, err = describeStack(cfClient, stackName)
+	err = checkStackAbsent(err)
+	if err != nil {
+		return err
+	}
+
+	changeSetName := "signet-plan-" + time.Now().UTC().Format("20060102150405")
+	changeSet, err := createChangeSet(cfClient, types.ChangeSetTypeCreate, changeSetName, template, toCloudFormationParameters(params))
+	defer deletePlannedStack(cfClient)
+	if err != nil {
+		return err
+	}
+
+	if changeSet.Status == types.ChangeSetStatusFailed {
+		return errors.New("unable to plan the deployment of stack " + stackName + ": " + aws.ToString(changeSet.StatusReason))
+	}
+
+	cmd.Println("Deploying stack " + stackName + " would create these resources:")
+	cmd.Println()
+	printChangeSet(cmd.OutOrStderr(), changeSet.Changes)
+	cmd.Println()
+	cmd.Println(colorGreen + "Dry run" + colorReset + " - the template and parameters are valid, and nothing was deployed")
+
+	return nil
+}
+
+// a new stack can only be previewed when describeStack found no stack, and any other error is returned
+func checkStackAbsent(describeErr error) error {
+	var notFound stackNotFoundError
+	if describeErr == nil {
+		return errors.New("stack " + stackName + " already exists - use --upgrade --dry-run to see the changes an upgrade would make")
+	}
+	if !errors.As(describeErr, &notFound) {
+		return describeErr
+	}
+	return nil
+}
+
+// only the empty stack of the change set is deleted, never a stack that was deployed in the meantime
+func deletePlannedStack(cfClient *cloudformation.Client) {
+	stack, err := describeStack(cfClient, stackName)
+	if err != nil || stack.StackStatus != types.StackStatusReviewInProgress {
+		return
+	}
+
+	_, err = cfClient.DeleteStack(context.TODO(), &cloudformation.DeleteStackInput{StackName: stack.StackId})
+	if err != nil {
+		fmt.Println(colorRed + "Warning" + colorReset + " - unable to delete the empty stack " + stackName + " of the preview, delete it before deploying: " + err.Error())
+		return
+	}
+
+	// deploy fails while a stack with the same name exists, so the preview waits until it is gone
+	waiter := cloudformation.NewStackDeleteCompleteWaiter(cfClient)
+	err = waiter.Wait(context.TODO(), &cloudformation.DescribeStacksInput{StackName: stack.StackId}, 5*time.Minute)
+	if err != nil {
+		fmt.Println("Info - the empty stack " + stackName + " of the preview is still being deleted, deploy will fail until it is gone")
+	}
+}
+
+// validateTemplate asks CloudFormation to check the template, which also catches mistakes in custom templates
+func validateTemplate(cfClient *cloudformation.Client, template string) error {
+	_, err := cfClient.ValidateTemplate(context.TODO(), &cloudformation.ValidateTemplateInput{TemplateBody: aws.String(template)})
+	if err != nil {
+		return errors.New("the CloudFormation template is not valid: " + err.Error())
+	}
+	return nil
+}
+
+// printDataLossWarnings lists the resources holding data that a change set would delete or replace
+func printDataLossWarnings(out io.Writer, changes []types.Change) {
+	warnings := []string{}
+	for _, change := range changes {
+		resource := change.ResourceChange
+		if resource == nil {
+			continue
+		}
+
+		holds, ok := dataResourceTypes[aws.ToString(resource.ResourceType)]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case resource.Action == types.ChangeActionRemove:
+			warnings = append(warnings, aws.ToString(resource.LogicalResourceId)+" will be deleted, losing "+holds)
+		case resource.Replacement == types.ReplacementTrue:
+			warnings = append(warnings, aws.ToString(resource.LogicalResourceId)+" will be replaced, losing "+holds)
+		case resource.Replacement == types.ReplacementConditional:
+			warnings = append(warnings, aws.ToString(resource.LogicalResourceId)+" may be replaced, losing "+holds)
+		}
+	}
+
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, colorRed+"Warning"+colorReset+" - these changes delete data:")
+	for _, warning := range warnings {
+		fmt.Fprintln(out, "  "+warning)
+	}
+	fmt.Fprintln(out)
+}
+
+/*
+validateUndeployConfirm requires --confirm to repeat the stack name, because
+deleting the stack deletes the broker's database along with it.
+*/
+func validateUndeployConfirm(confirmName string) error {
+	if len(confirmName) == 0 {
+		return errors.New("undeploy permanently deletes stack " + stackName + ", including the broker's database - pass --confirm " + stackName + " to delete it, or --dry-run to see what would be deleted")
+	}
+	if confirmName != stackName {
+		return errors.New("--confirm " + confirmName + " does not match the stack name " + stackName)
+	}
+	return nil
+}
+
+// previewUndeploy lists the resources undeploy would delete, and which of them hold data
+func previewUndeploy(cfClient *cloudformation.Client) error {
+	if _, err := describeStack(cfClient, stackName); err != nil {
+		return err
+	}
+
+	dsrOutput, err := cfClient.DescribeStackResources(context.TODO(), &cloudformation.DescribeStackResourcesInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return errors.New("unable to list the resources of stack " + stackName + ": " + err.Error())
+	}
+
+	fmt.Println("Undeploying stack " + stackName + " would delete these resources:")
+	fmt.Println()
+	printStackResources(os.Stdout, dsrOutput.StackResources)
+	fmt.Println()
+	fmt.Println(colorGreen + "Dry run" + colorReset + " - nothing was deleted, run undeploy with --confirm " + stackName + " to delete the stack")
+
+	return nil
+}
+
+func printStackResources(out io.Writer, resources []types.StackResource) {
+	warnings := []string{}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RESOURCE\tTYPE\tPHYSICAL ID")
+	for _, resource := range resources {
+		resourceType := aws.ToString(resource.ResourceType)
+		physicalID := aws.ToString(resource.PhysicalResourceId)
+		if len(physicalID) == 0 {
+			physicalID = "-"
+		}
+
+		fmt.Fprintln(writer, aws.ToString(resource.LogicalResourceId)+"\t"+resourceType+"\t"+physicalID)
+
+		if holds, ok := dataResourceTypes[resourceType]; ok {
+			warnings = append(warnings, aws.ToString(resource.LogicalResourceId)+" holds "+holds)
+		}
+	}
+	writer.Flush()
+
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, colorRed+"Warning"+colorReset+" - this data is permanently lost when the stack is deleted:")
+	for _, warning := range warnings {
+		fmt.Fprintln(out, "  "+warning)
+	}
+}
+
+func init() {
+	deployCmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the template and parameters, and show the planned changes without making them (optional)")
+	undeployCmd.Flags().BoolVar(&undeployDryRun, "dry-run", false, "show the resources that would be deleted without deleting them (optional)")
+	undeployCmd.Flags().StringVar(&undeployConfirm, "confirm", "", "the name of the stack being deleted, to confirm that it and its data should be deleted")
+
+	viper.BindPFlag("deploy.dry-run", deployCmd.Flags().Lookup("dry-run"))
+	viper.BindPFlag("undeploy.dry-run", undeployCmd.Flags().Lookup("dry-run"))
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// status flags are one-off actions, so they are not read from .signetrc.yaml
+var detectDrift bool
+var writeConfig bool
+
+var deployStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of a Signet broker deployment",
+	Long: `Show the status of the CloudFormation stack of a Signet broker deployment, whether its resources have drifted from the template, the health of the broker's ECS service, and the URL of the broker
+
+	flags:
+
+	--stack-name        the name of the CloudFormation stack (optional, defaults to signetbroker)
+
+	--detect-drift      check the stack's resources for drift from the template now, rather than showing the result of the last check (optional)
+
+	--write-config      write the URL of the broker to .signetrc.yaml as broker-url, so that other commands use this broker (optional)
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stackName = viper.GetString("deploy.stack-name")
+		err := validateStackName(stackName)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadDefaultConfig(context.TODO())
+		if err != nil {
+			return errors.New("unable to load SDK config - have you configured your aws cli with `aws configure sso`? Have you logged in with `aws login sso`?" + err.Error())
+		}
+
+		cfClient := cloudformation.NewFromConfig(cfg)
+		stack, err := describeStack(cfClient, stackName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Stack:       " + *stack.StackName)
+		fmt.Println("Status:      " + describeStackStatus(stack))
+		fmt.Println("Created:     " + stack.CreationTime.Format(time.RFC3339))
+		if stack.LastUpdatedTime != nil {
+			fmt.Println("Updated:     " + stack.LastUpdatedTime.Format(time.RFC3339))
+		}
+
+		if detectDrift {
+			fmt.Println("Checking for drift, this can take a minute...")
+			drifts, err := detectStackDrift(cfClient, stackName)
+			if err != nil {
+				fmt.Println("Drift:       unavailable - " + err.Error())
+			} else {
+				fmt.Println("Drift:       " + describeResourceDrifts(drifts))
+				for _, drift := range drifts {
+					fmt.Println("             " + string(drift.StackResourceDriftStatus) + "  " + aws.ToString(drift.LogicalResourceId) + " (" + aws.ToString(drift.ResourceType) + ")")
+				}
+			}
+		} else {
+			fmt.Println("Drift:       " + describeStackDrift(stack.DriftInformation))
+		}
+
+		service, err := describeBrokerService(cfClient, ecs.NewFromConfig(cfg), stack)
+		if err != nil {
+			fmt.Println("Service:     unavailable - " + err.Error())
+		} else {
+			fmt.Println("Service:     " + aws.ToString(service.ServiceName) + " (" + aws.ToString(service.Status) + ")")
+			fmt.Println("Tasks:       " + strconv.Itoa(int(service.RunningCount)) + " running of " + strconv.Itoa(int(service.DesiredCount)) + " desired, " + strconv.Itoa(int(service.PendingCount)) + " pending")
+			fmt.Println("Health:      " + describeServiceHealth(service))
+			if len(service.Events) != 0 {
+				fmt.Println("Last event:  " + service.Events[0].CreatedAt.Format(time.RFC3339) + " " + aws.ToString(service.Events[0].Message))
+			}
+		}
+
+		stackBrokerURL, err := getBrokerURLofStack(cfClient, cfg, stackName)
+		if err != nil {
+			fmt.Println("Broker URL:  unavailable - " + err.Error())
+			if writeConfig {
+				return errors.New("unable to write broker-url to " + configFileName + ", because the URL of the broker is unavailable")
+			}
+			return nil
+		}
+		fmt.Println("Broker URL:  " + colorBlue + stackBrokerURL + colorReset)
+
+		if writeConfig {
+			err = writeBrokerURLToConfig(configFileName, stackBrokerURL)
+			if err != nil {
+				return err
+			}
+			fmt.Println("\nWrote broker-url to " + configFileName)
+		}
+
+		return nil
+	},
+}
+
+// returned by describeStack, so that callers can tell a missing stack from a failed request
+type stackNotFoundError struct {
+	stackName string
+}
+
+func (e stackNotFoundError) Error() string {
+	return "stack " + e.stackName + " was not found - run `signet deploy list` to see the deployed brokers"
+}
+
+// CloudFormation reports a missing stack as a ValidationError, which it also uses for invalid requests
+func isStackNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "does not exist")
+}
+
+func describeStack(cfClient *cloudformation.Client, stackName string) (types.Stack, error) {
+	dsOutput, err := cfClient.DescribeStacks(context.TODO(), &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)})
+	if err != nil {
+		if isStackNotFound(err) {
+			return types.Stack{}, stackNotFoundError{stackName}
+		}
+		return types.Stack{}, errors.New("unable to describe CloudFormation stack " + stackName + ": " + err.Error())
+	}
+
+	if len(dsOutput.Stacks) == 0 {
+		return types.Stack{}, stackNotFoundError{stackName}
+	}
+
+	return dsOutput.Stacks[0], nil
+}
+
+func describeStackStatus(stack types.Stack) string {
+	status := string(stack.StackStatus)
+
+	color := colorGreen
+	if strings.HasSuffix(status, "_IN_PROGRESS") {
+		color = colorBlue
+	} else if strings.Contains(status, "FAILED") || strings.Contains(status, "ROLLBACK") {
+		color = colorRed
+	}
+
+	status = color + status + colorReset
+	if stack.StackStatusReason != nil {
+		status += " (" + *stack.StackStatusReason + ")"
+	}
+
+	return status
+}
+
+// describeStackDrift shows the result of the last drift detection, which DescribeStacks includes
+func describeStackDrift(drift *types.StackDriftInformation) string {
+	if drift == nil || drift.StackDriftStatus == types.StackDriftStatusNotChecked || len(drift.StackDriftStatus) == 0 {
+		return "not checked - run with --detect-drift to check"
+	}
+
+	status := colorGreen + string(drift.StackDriftStatus) + colorReset
+	if drift.StackDriftStatus == types.StackDriftStatusDrifted {
+		status = colorRed + string(drift.StackDriftStatus) + colorReset + " - run with --detect-drift to see which resources drifted"
+	}
+
+	if drift.LastCheckTimestamp != nil {
+		status += " (checked " + drift.LastCheckTimestamp.Format(time.RFC3339) + ")"
+	}
+	return status
+}
+
+func describeResourceDrifts(drifts []types.StackResourceDrift) string {
+	if len(drifts) == 0 {
+		return colorGreen + string(types.StackDriftStatusInSync) + colorReset
+	}
+	return colorRed + string(types.StackDriftStatusDrifted) + colorReset + " - " + strconv.Itoa(len(drifts)) + " resources were changed outside of CloudFormation"
+}
+
+// detectStackDrift checks the stack's resources for drift, and returns the ones that were modified or deleted
+func detectStackDrift(cfClient *cloudformation.Client, stackName string) ([]types.StackResourceDrift, error) {
+	dsdOutput, err := cfClient.DetectStackDrift(context.TODO(), &cloudformation.DetectStackDriftInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(5 * time.Minute)
+	for {
+		statusOutput, err := cfClient.DescribeStackDriftDetectionStatus(context.TODO(), &cloudformation.DescribeStackDriftDetectionStatusInput{StackDriftDetectionId: dsdOutput.StackDriftDetectionId})
+		if err != nil {
+			return nil, err
+		}
+
+		if statusOutput.DetectionStatus == types.StackDriftDetectionStatusDetectionFailed {
+			return nil, errors.New("drift detection failed: " + aws.ToString(statusOutput.DetectionStatusReason))
+		}
+		if statusOutput.DetectionStatus != types.StackDriftDetectionStatusDetectionInProgress {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for drift detection")
+		}
+
+		time.Sleep(stackPollInterval)
+	}
+
+	drifts := []types.StackResourceDrift{}
+	drInput := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName:                       aws.String(stackName),
+		StackResourceDriftStatusFilters: []types.StackResourceDriftStatus{types.StackResourceDriftStatusModified, types.StackResourceDriftStatusDeleted},
+	}
+	paginator := cloudformation.NewDescribeStackResourceDriftsPaginator(cfClient, drInput)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, page.StackResourceDrifts...)
+	}
+
+	return drifts, nil
+}
+
+/*
+describeBrokerService finds the ECS service of the broker from the ClusterName
+and ServiceName outputs of the stack, if its template has them, or else from
+the stack's ECS cluster and service resources.
+*/
+func describeBrokerService(cfClient *cloudformation.Client, ecsClient *ecs.Client, stack types.Stack) (ecstypes.Service, error) {
+	clusterName := stackOutput(stack, "ClusterName")
+	serviceName := stackOutput(stack, "ServiceName")
+
+	if len(clusterName) == 0 || len(serviceName) == 0 {
+		dsrInput := &cloudformation.DescribeStackResourcesInput{StackName: stack.StackName}
+		dsrOutput, err := cfClient.DescribeStackResources(context.TODO(), dsrInput)
+		if err != nil {
+			return ecstypes.Service{}, err
+		}
+
+		// the physical ID of a cluster is its name, and of a service its ARN, which DescribeServices accepts
+		for _, resource := range dsrOutput.StackResources {
+			switch aws.ToString(resource.ResourceType) {
+			case "AWS::ECS::Cluster":
+				clusterName = aws.ToString(resource.PhysicalResourceId)
+			case "AWS::ECS::Service":
+				serviceName = aws.ToString(resource.PhysicalResourceId)
+			}
+		}
+	}
+
+	if len(clusterName) == 0 || len(serviceName) == 0 {
+		return ecstypes.Service{}, errors.New("stack " + aws.ToString(stack.StackName) + " has no ECS service")
+	}
+
+	dsOutput, err := ecsClient.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
+		Cluster:  aws.String(clusterName),
+		Services: []string{serviceName},
+	})
+	if err != nil {
+		return ecstypes.Service{}, err
+	}
+	if len(dsOutput.Services) == 0 {
+		return ecstypes.Service{}, errors.New("service " + serviceName + " was not found in cluster " + clusterName)
+	}
+
+	return dsOutput.Services[0], nil
+}
+
+func stackOutput(stack types.Stack, key string) string {
+	for _, output := range stack.Outputs {
+		if aws.ToString(output.OutputKey) == key {
+			return aws.ToString(output.OutputValue)
+		}
+	}
+	return ""
+}
+
+// describeServiceHealth summarizes the service's tasks and its latest rollout
+func describeServiceHealth(service ecstypes.Service) string {
+	var primary *ecstypes.Deployment
+	for i, deployment := range service.Deployments {
+		if aws.ToString(deployment.Status) == "PRIMARY" {
+			primary = &service.Deployments[i]
+		}
+	}
+
+	switch {
+	case primary != nil && primary.RolloutState == ecstypes.DeploymentRolloutStateFailed:
+		return colorRed + "unhealthy" + colorReset + " - the latest rollout failed: " + aws.ToString(primary.RolloutStateReason)
+	case service.RunningCount == 0:
+		return colorRed + "unhealthy" + colorReset + " - no broker tasks are running"
+	case primary != nil && primary.RolloutState == ecstypes.DeploymentRolloutStateInProgress:
+		return colorBlue + "rolling out" + colorReset + " - " + strconv.Itoa(int(primary.RunningCount)) + " of " + strconv.Itoa(int(primary.DesiredCount)) + " tasks of the new deployment are running"
+	case service.RunningCount < service.DesiredCount:
+		return colorRed + "degraded" + colorReset + " - " + strconv.Itoa(int(service.RunningCount)) + " of " + strconv.Itoa(int(service.DesiredCount)) + " tasks are running"
+	}
+
+	return colorGreen + "healthy" + colorReset
+}
+
+var brokerURLConfigPattern = regexp.MustCompile(`(?m)^broker-url:.*$`)
+
+/*
+writeBrokerURLToConfig sets broker-url in the config file, creating the file if
+it does not exist. The file is edited as text, so that its comments and the
+order of its settings are kept.
+*/
+func writeBrokerURLToConfig(path, stackBrokerURL string) error {
+	setting := "broker-url: " + stackBrokerURL
+
+	configBytes, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.New("unable to read " + path + ": " + err.Error())
+	}
+
+	configText := string(configBytes)
+	if brokerURLConfigPattern.MatchString(configText) {
+		configText = brokerURLConfigPattern.ReplaceAllLiteralString(configText, setting)
+	} else {
+		configText = setting + "\n" + configText
+	}
+
+	err = os.WriteFile(path, []byte(configText), 0644)
+	if err != nil {
+		return errors.New("unable to write " + path + ": " + err.Error())
+	}
+
+	return nil
+}
+
+func init() {
+	deployCmd.AddCommand(deployStatusCmd)
+
+	deployStatusCmd.Flags().BoolVar(&detectDrift, "detect-drift", false, "check the stack's resources for drift from the template now (optional)")
+	deployStatusCmd.Flags().BoolVar(&writeConfig, "write-config", false, "write the URL of the broker to .signetrc.yaml as broker-url (optional)")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var deployTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Print the CloudFormation template used by signet deploy",
+	Long: `Print the CloudFormation template that signet deploy uses to provision the Signet broker, to review it or to check it into an infrastructure repository
+
+	the printed template can be customized and deployed with signet deploy --template
+
+	flags:
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template, err := getCloudFormationTemplate("")
+		if err != nil {
+			return err
+		}
+
+		cmd.OutOrStdout().Write([]byte(template))
+		return nil
+	},
+}
+
+func init() {
+	deployCmd.AddCommand(deployTemplateCmd)
+}
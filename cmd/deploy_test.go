@@ -0,0 +1,712 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/smithy-go"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+func callSignetDeploy(argsAndFlags []string) actualOut {
+	actual := new(bytes.Buffer)
+	RootCmd.SetOut(actual)
+	RootCmd.SetErr(actual)
+	RootCmd.SetArgs(append([]string{"deploy"}, argsAndFlags...))
+	RootCmd.Execute()
+	return actualOut{actual.String()}
+}
+
+func TestSignetDeployInvalidStackName(t *testing.T) {
+	actual := callSignetDeploy([]string{"--stack-name", "signet_broker"})
+	expected := "Error: --stack-name must start with a letter, and only contain letters, numbers, and dashes"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestResolveStackName(t *testing.T) {
+	t.Run("defaults to signetbroker", func(t *testing.T) {
+		if resolveStackName("undeploy.stack-name") != "signetbroker" {
+			t.Error(resolveStackName("undeploy.stack-name"))
+		}
+	})
+
+	t.Run("falls back to deploy.stack-name", func(t *testing.T) {
+		viper.Set("deploy.stack-name", "signet-staging")
+		defer viper.Set("deploy.stack-name", nil)
+
+		if resolveStackName("undeploy.stack-name") != "signet-staging" {
+			t.Error(resolveStackName("undeploy.stack-name"))
+		}
+	})
+
+	t.Run("prefers the command's own stack name", func(t *testing.T) {
+		viper.Set("deploy.stack-name", "signet-staging")
+		viper.Set("undeploy.stack-name", "signet-production")
+		defer viper.Set("deploy.stack-name", nil)
+		defer viper.Set("undeploy.stack-name", nil)
+
+		if resolveStackName("undeploy.stack-name") != "signet-production" {
+			t.Error(resolveStackName("undeploy.stack-name"))
+		}
+	})
+}
+
+func TestIsBrokerStack(t *testing.T) {
+	tagged := types.Stack{
+		StackName: aws.String("signet-staging"),
+		Tags:      []types.Tag{{Key: aws.String("signet-framework"), Value: aws.String("broker")}},
+	}
+	untagged := types.Stack{StackName: aws.String("some-other-stack")}
+	legacy := types.Stack{StackName: aws.String("signetbroker")}
+
+	if !isBrokerStack(tagged) || isBrokerStack(untagged) || !isBrokerStack(legacy) {
+		t.Error()
+	}
+}
+
+// the embedded template is used without the signet-cli npm package
+func mockNoNpmPkg() func() {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	getNpmPkgRoot = func() (string, error) { return "", errors.New("npm root -g failed") }
+
+	return func() { getNpmPkgRoot = realGetNpmPkgRoot }
+}
+
+func TestSignetDeployTemplate(t *testing.T) {
+	restore := mockNoNpmPkg()
+	defer restore()
+
+	actual := callSignetDeploy([]string{"template"})
+
+	var template map[string]interface{}
+	err := yaml.Unmarshal([]byte(actual.actual), &template)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("prints the embedded template", func(t *testing.T) {
+		if actual.actual != embeddedTemplate {
+			t.Error(actual.actual)
+		}
+	})
+
+	t.Run("has the load balancer used to find the broker URL", func(t *testing.T) {
+		resources, _ := template["Resources"].(map[interface{}]interface{})
+		loadBalancer, _ := resources["LoadBalancer"].(map[interface{}]interface{})
+
+		if loadBalancer["Type"] != "AWS::ElasticLoadBalancingV2::LoadBalancer" {
+			t.Error(loadBalancer)
+		}
+	})
+
+	teardown()
+}
+
+func TestGetCloudFormationTemplate(t *testing.T) {
+	t.Run("defaults to the embedded template without the npm package", func(t *testing.T) {
+		restore := mockNoNpmPkg()
+		defer restore()
+
+		template, err := getCloudFormationTemplate("")
+		if err != nil || template != embeddedTemplate {
+			t.Error(err)
+		}
+	})
+
+	t.Run("reads a custom template", func(t *testing.T) {
+		customPath := filepath.Join(t.TempDir(), "custom.yaml")
+		os.WriteFile(customPath, []byte("Resources: {}\n"), 0644)
+
+		template, err := getCloudFormationTemplate(customPath)
+		if err != nil || template != "Resources: {}\n" {
+			t.Error(err, template)
+		}
+	})
+
+	t.Run("missing custom templates are an error", func(t *testing.T) {
+		_, err := getCloudFormationTemplate(filepath.Join(t.TempDir(), "missing.yaml"))
+		if err == nil || !strings.HasPrefix(err.Error(), "unable to load CloudFormation template") {
+			t.Error(err)
+		}
+	})
+}
+
+func TestGetStackParameters(t *testing.T) {
+	t.Run("reads a parameters file", func(t *testing.T) {
+		params, err := getStackParameters("../data_test/stack-parameters.yaml", "us-east-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if params["LoadBalancerSubnetIds"] != "subnet-0a1b2c3d,subnet-4e5f6a7b" || params["TaskCpu"] != "1024" || params["Region"] != "us-east-1" {
+			t.Error(params)
+		}
+	})
+
+	t.Run("reads the AWS CLI parameters format", func(t *testing.T) {
+		cliPath := filepath.Join(t.TempDir(), "parameters.json")
+		os.WriteFile(cliPath, []byte(`[{"ParameterKey": "TaskCpu", "ParameterValue": "2048"}]`), 0644)
+
+		params, err := getStackParameters(cliPath, "us-east-1")
+		if err != nil || params["TaskCpu"] != "2048" {
+			t.Error(err, params)
+		}
+	})
+
+	t.Run("flags override the parameters file", func(t *testing.T) {
+		viper.Set("deploy.task-cpu", "2048")
+		viper.Set("deploy.service-subnets", []string{"subnet-1", "subnet-2"})
+		defer viper.Set("deploy.task-cpu", nil)
+		defer viper.Set("deploy.service-subnets", nil)
+
+		params, err := getStackParameters("../data_test/stack-parameters.yaml", "us-east-1")
+		if err != nil || params["TaskCpu"] != "2048" || params["ServiceSubnetIds"] != "subnet-1,subnet-2" || params["TaskMemory"] != "2048" {
+			t.Error(err, params)
+		}
+	})
+
+	t.Run("missing parameters files are an error", func(t *testing.T) {
+		_, err := getStackParameters(filepath.Join(t.TempDir(), "missing.yaml"), "us-east-1")
+		if err == nil || !strings.HasPrefix(err.Error(), "unable to read parameters file") {
+			t.Error(err)
+		}
+	})
+}
+
+func TestValidateStackParameters(t *testing.T) {
+	t.Run("accepts the parameters file", func(t *testing.T) {
+		params, _ := getStackParameters("../data_test/stack-parameters.yaml", "us-east-1")
+
+		if err := validateStackParameters(params, embeddedTemplate); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("accepts the template defaults", func(t *testing.T) {
+		if err := validateStackParameters(map[string]string{"Region": "us-east-1"}, embeddedTemplate); err != nil {
+			t.Error(err)
+		}
+	})
+
+	cases := []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{
+		{"unknown parameters", map[string]string{"TaskCPU": "512"}, "TaskCPU is not a parameter of the template"},
+		{"numbers", map[string]string{"DesiredCount": "two"}, `DesiredCount must be a number, got "two"`},
+		{"minimum values", map[string]string{"DatabaseAllocatedStorage": "10"}, "DatabaseAllocatedStorage must be at least 20, got 10"},
+		{"maximum values", map[string]string{"DatabaseBackupRetentionDays": "90"}, "DatabaseBackupRetentionDays must be at most 35, got 90"},
+		{"allowed values", map[string]string{"LogRetentionDays": "45"}, "LogRetentionDays must be one of 1, 3, 5"},
+		{"patterns", map[string]string{"DatabaseInstanceClass": "t3.micro"}, "DatabaseInstanceClass must match"},
+		{"Fargate sizes", map[string]string{"TaskCpu": "256", "TaskMemory": "4096"}, "TaskMemory 4096 is not valid for TaskCpu 256 on Fargate, it must be between 512 and 2048 MiB"},
+		{"subnets without a VPC", map[string]string{"ServiceSubnetIds": "subnet-1,subnet-2"}, "ServiceSubnetIds can only be set with VpcId"},
+		{"a VPC without subnets", map[string]string{"VpcId": "vpc-0a1b2c3d", "LoadBalancerSubnetIds": "subnet-1,subnet-2"}, "ServiceSubnetIds must have subnets in at least two availability zones when VpcId is set"},
+		{"subnet IDs", map[string]string{"VpcId": "vpc-0a1b2c3d", "LoadBalancerSubnetIds": "subnet-1,sg-2", "ServiceSubnetIds": "subnet-3,subnet-4"}, `LoadBalancerSubnetIds must be subnet IDs, got "sg-2"`},
+	}
+
+	for _, c := range cases {
+		t.Run("checks "+c.name, func(t *testing.T) {
+			c.params["Region"] = "us-east-1"
+
+			err := validateStackParameters(c.params, embeddedTemplate)
+			if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedTemplateDeclaresParameterFlags(t *testing.T) {
+	templateParams, err := utils.LoadTemplateParameters(embeddedTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mapping := range stackParameterFlags {
+		param, ok := templateParams[mapping.parameter]
+		if !ok || param.Default == nil {
+			t.Error("--" + mapping.flag + " sets " + mapping.parameter + ", which the embedded template does not declare with a default")
+		}
+	}
+
+	t.Run("accepts the README examples", func(t *testing.T) {
+		for _, flag := range []string{"task-cpu", "task-memory", "desired-count", "db-instance-class", "db-backup-retention", "image"} {
+			defer viper.Set("deploy."+flag, nil)
+		}
+		viper.Set("deploy.task-cpu", "1024")
+		viper.Set("deploy.task-memory", "2048")
+		viper.Set("deploy.desired-count", "2")
+		viper.Set("deploy.db-instance-class", "db.t3.small")
+		viper.Set("deploy.db-backup-retention", "14")
+		viper.Set("deploy.image", "registry.example.com/signet-broker:1.2.0")
+
+		params, _ := getStackParameters("", "us-east-1")
+		if err := validateStackParameters(params, embeddedTemplate); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("the broker task runs the image of BrokerImage", func(t *testing.T) {
+		var template struct {
+			Resources map[string]struct {
+				Properties struct {
+					ContainerDefinitions []struct {
+						Image map[string]string `yaml:"Image"`
+					} `yaml:"ContainerDefinitions"`
+				} `yaml:"Properties"`
+			} `yaml:"Resources"`
+		}
+		yaml.Unmarshal([]byte(embeddedTemplate), &template)
+
+		containers := template.Resources["TaskDefinition"].Properties.ContainerDefinitions
+		if len(containers) != 1 || containers[0].Image["Ref"] != "BrokerImage" {
+			t.Error(containers)
+		}
+	})
+}
+
+func TestValidateStackParametersNamesFlags(t *testing.T) {
+	template := "Parameters:\n  Region:\n    Type: String\n"
+
+	err := validateStackParameters(map[string]string{"Region": "us-east-1", "VpcId": "vpc-0a1b2c3d"}, template)
+	expected := "--vpc-id sets the VpcId parameter, which the template does not declare - deploy a template that declares it with --template"
+	if err == nil || !strings.Contains(err.Error(), expected) || strings.Contains(err.Error(), "VpcId is not a parameter of the template") {
+		t.Error(err)
+	}
+}
+
+func TestSignetDeployInvalidParameters(t *testing.T) {
+	actual := callSignetDeploy([]string{"--task-cpu", "256", "--task-memory", "8192"})
+	expected := "Error: invalid stack parameters:\n  TaskMemory 8192 is not valid for TaskCpu 256 on Fargate"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetDeployYesWithoutUpgrade(t *testing.T) {
+	actual := callSignetDeploy([]string{"--yes"})
+	expected := "Error: --yes can only be used with --upgrade"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestUpgradeParameters(t *testing.T) {
+	templateParams, _ := utils.LoadTemplateParameters(embeddedTemplate)
+	previous := []types.Parameter{
+		{ParameterKey: aws.String("Region"), ParameterValue: aws.String("us-east-1")},
+		{ParameterKey: aws.String("TaskCpu"), ParameterValue: aws.String("1024")},
+		{ParameterKey: aws.String("TaskMemory"), ParameterValue: aws.String("4096")},
+		{ParameterKey: aws.String("RemovedParameter"), ParameterValue: aws.String("value")},
+	}
+
+	cfParams, allParams := upgradeParameters(map[string]string{"Region": "us-east-1", "TaskMemory": "8192"}, previous, templateParams)
+
+	t.Run("given parameters are set", func(t *testing.T) {
+		if allParams["TaskMemory"] != "8192" {
+			t.Error(allParams)
+		}
+	})
+
+	t.Run("other parameters use their previous value", func(t *testing.T) {
+		usesPrevious := map[string]bool{}
+		for _, param := range cfParams {
+			if aws.ToBool(param.UsePreviousValue) {
+				usesPrevious[*param.ParameterKey] = true
+			}
+		}
+
+		if !usesPrevious["TaskCpu"] || usesPrevious["TaskMemory"] || usesPrevious["Region"] || allParams["TaskCpu"] != "1024" {
+			t.Error(usesPrevious, allParams)
+		}
+	})
+
+	t.Run("parameters removed from the template are dropped", func(t *testing.T) {
+		if _, ok := allParams["RemovedParameter"]; ok || len(cfParams) != 3 {
+			t.Error(cfParams)
+		}
+	})
+
+	t.Run("previous values are validated with the given parameters", func(t *testing.T) {
+		_, allParams := upgradeParameters(map[string]string{"Region": "us-east-1", "TaskMemory": "30720"}, previous, templateParams)
+
+		err := validateStackParameters(allParams, embeddedTemplate)
+		if err == nil || !strings.Contains(err.Error(), "TaskMemory 30720 is not valid for TaskCpu 1024") {
+			t.Error(err)
+		}
+	})
+}
+
+func TestPrintChangeSet(t *testing.T) {
+	changes := []types.Change{
+		{ResourceChange: &types.ResourceChange{
+			Action:            types.ChangeActionModify,
+			LogicalResourceId: aws.String("TaskDefinition"),
+			ResourceType:      aws.String("AWS::ECS::TaskDefinition"),
+			Replacement:       types.ReplacementTrue,
+		}},
+		{ResourceChange: &types.ResourceChange{
+			Action:            types.ChangeActionAdd,
+			LogicalResourceId: aws.String("LogGroup"),
+			ResourceType:      aws.String("AWS::Logs::LogGroup"),
+		}},
+	}
+
+	actual := new(bytes.Buffer)
+	printChangeSet(actual, changes)
+	lines := strings.Split(strings.TrimSpace(actual.String()), "\n")
+
+	if len(lines) != 3 || strings.Fields(lines[1])[0] != "Modify" || strings.Fields(lines[1])[3] != "True" || strings.Fields(lines[2])[3] != "-" {
+		t.Error(actual.String())
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		out := new(bytes.Buffer)
+		if confirm(strings.NewReader(answer), out, "Execute these changes?") != expected {
+			t.Error(answer)
+		}
+
+		if out.String() != "Execute these changes? [y/N] " {
+			t.Error(out.String())
+		}
+	}
+}
+
+func TestConfirmChangeSet(t *testing.T) {
+	stackName = "signet-staging"
+	defer teardown()
+
+	t.Run("a confirmed change set is executed", func(t *testing.T) {
+		if err := confirmChangeSet(strings.NewReader("y\n"), io.Discard); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("a declined change set is an error", func(t *testing.T) {
+		err := confirmChangeSet(strings.NewReader("n\n"), io.Discard)
+		if err == nil || !strings.HasPrefix(err.Error(), "the upgrade of stack signet-staging was not confirmed") {
+			t.Error(err)
+		}
+	})
+
+	t.Run("stdin without an answer is an error", func(t *testing.T) {
+		if err := confirmChangeSet(strings.NewReader(""), io.Discard); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("--yes does not ask", func(t *testing.T) {
+		assumeYes = true
+		out := new(bytes.Buffer)
+		if err := confirmChangeSet(strings.NewReader(""), out); err != nil || out.Len() != 0 {
+			t.Error(err, out.String())
+		}
+	})
+}
+
+func TestIsNoChangesReason(t *testing.T) {
+	if !isNoChangesReason("The submitted information didn't contain changes. Submit different information to create a change set.") || isNoChangesReason("Parameter TaskCpu has an invalid value") {
+		t.Error()
+	}
+}
+
+func callSignetUndeploy(argsAndFlags []string) actualOut {
+	actual := new(bytes.Buffer)
+	RootCmd.SetOut(actual)
+	RootCmd.SetErr(actual)
+	RootCmd.SetArgs(append([]string{"undeploy"}, argsAndFlags...))
+	RootCmd.Execute()
+	return actualOut{actual.String()}
+}
+
+func TestSignetUndeployWithoutConfirm(t *testing.T) {
+	actual := callSignetUndeploy([]string{"--stack-name", "signet-staging"})
+	expected := "Error: undeploy permanently deletes stack signet-staging, including the broker's database - pass --confirm signet-staging to delete it"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetUndeployConfirmMismatch(t *testing.T) {
+	actual := callSignetUndeploy([]string{"--stack-name", "signet-staging", "--confirm", "signetbroker"})
+	expected := "Error: --confirm signetbroker does not match the stack name signet-staging"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestPrintStackResources(t *testing.T) {
+	resources := []types.StackResource{
+		{LogicalResourceId: aws.String("Cluster"), ResourceType: aws.String("AWS::ECS::Cluster"), PhysicalResourceId: aws.String("signetbroker-Cluster")},
+		{LogicalResourceId: aws.String("Database"), ResourceType: aws.String("AWS::RDS::DBInstance"), PhysicalResourceId: aws.String("signetbroker-database")},
+	}
+
+	actual := new(bytes.Buffer)
+	printStackResources(actual, resources)
+
+	if !strings.Contains(actual.String(), "Cluster   AWS::ECS::Cluster") || !strings.Contains(actual.String(), "this data is permanently lost") || !strings.Contains(actual.String(), "  Database holds the broker's database") || strings.Contains(actual.String(), "Cluster holds") {
+		t.Error(actual.String())
+	}
+}
+
+func TestPrintDataLossWarnings(t *testing.T) {
+	t.Run("warns when data is deleted or replaced", func(t *testing.T) {
+		changes := []types.Change{
+			{ResourceChange: &types.ResourceChange{Action: types.ChangeActionModify, LogicalResourceId: aws.String("Database"), ResourceType: aws.String("AWS::RDS::DBInstance"), Replacement: types.ReplacementTrue}},
+			{ResourceChange: &types.ResourceChange{Action: types.ChangeActionRemove, LogicalResourceId: aws.String("LogGroup"), ResourceType: aws.String("AWS::Logs::LogGroup")}},
+			{ResourceChange: &types.ResourceChange{Action: types.ChangeActionModify, LogicalResourceId: aws.String("Service"), ResourceType: aws.String("AWS::ECS::Service"), Replacement: types.ReplacementTrue}},
+		}
+
+		actual := new(bytes.Buffer)
+		printDataLossWarnings(actual, changes)
+
+		if !strings.Contains(actual.String(), "Database will be replaced") || !strings.Contains(actual.String(), "LogGroup will be deleted") || strings.Contains(actual.String(), "Service") {
+			t.Error(actual.String())
+		}
+	})
+
+	t.Run("is quiet when data is kept", func(t *testing.T) {
+		changes := []types.Change{
+			{ResourceChange: &types.ResourceChange{Action: types.ChangeActionModify, LogicalResourceId: aws.String("Database"), ResourceType: aws.String("AWS::RDS::DBInstance"), Replacement: types.ReplacementFalse}},
+		}
+
+		actual := new(bytes.Buffer)
+		printDataLossWarnings(actual, changes)
+
+		if actual.Len() != 0 {
+			t.Error(actual.String())
+		}
+	})
+}
+
+func stackEvent(id, logicalID, resourceType string, status types.ResourceStatus, reason string, second int) types.StackEvent {
+	event := types.StackEvent{
+		EventId:            aws.String(id),
+		LogicalResourceId:  aws.String(logicalID),
+		PhysicalResourceId: aws.String(logicalID + "-physical"),
+		ResourceType:       aws.String(resourceType),
+		ResourceStatus:     status,
+		Timestamp:          aws.Time(time.Date(2023, 7, 1, 12, 0, second, 0, time.UTC)),
+	}
+	if len(reason) != 0 {
+		event.ResourceStatusReason = aws.String(reason)
+	}
+	return event
+}
+
+func TestStackEventTracker(t *testing.T) {
+	tracker := newStackEventTracker("arn:aws:cloudformation:us-east-1:123456789012:stack/signetbroker/1")
+	tracker.seen["old"] = true
+
+	// DescribeStackEvents returns the newest events first
+	events := tracker.add([]types.StackEvent{
+		stackEvent("4", "Cluster", "AWS::ECS::Cluster", types.ResourceStatusCreateFailed, "Resource creation cancelled", 4),
+		stackEvent("3", "Database", "AWS::RDS::DBInstance", types.ResourceStatusCreateFailed, "DB instance class db.t9.micro is not supported", 3),
+		stackEvent("2", "Cluster", "AWS::ECS::Cluster", types.ResourceStatusCreateInProgress, "", 2),
+		stackEvent("1", "VPC", "AWS::EC2::VPC", types.ResourceStatusCreateComplete, "", 1),
+		stackEvent("old", "VPC", "AWS::EC2::VPC", types.ResourceStatusDeleteComplete, "", 0),
+	})
+
+	t.Run("returns new events oldest first", func(t *testing.T) {
+		if len(events) != 4 || *events[0].EventId != "1" || *events[3].EventId != "4" {
+			t.Error(events)
+		}
+	})
+
+	t.Run("does not return events twice", func(t *testing.T) {
+		again := tracker.add([]types.StackEvent{stackEvent("4", "Cluster", "AWS::ECS::Cluster", types.ResourceStatusCreateFailed, "Resource creation cancelled", 4)})
+		if len(again) != 0 {
+			t.Error(again)
+		}
+	})
+
+	t.Run("summarizes the latest status of each resource", func(t *testing.T) {
+		if tracker.summary() != "1 of 3 resources complete, 2 failed" {
+			t.Error(tracker.summary())
+		}
+	})
+
+	t.Run("reports the first failure that was not cancelled", func(t *testing.T) {
+		expected := "Database (AWS::RDS::DBInstance) CREATE_FAILED: DB instance class db.t9.micro is not supported"
+		if tracker.failureReason() != expected {
+			t.Error(tracker.failureReason())
+		}
+	})
+
+	t.Run("does not count the stack as a resource", func(t *testing.T) {
+		stackEvent := stackEvent("5", "signetbroker", "AWS::CloudFormation::Stack", types.ResourceStatus("ROLLBACK_IN_PROGRESS"), "", 5)
+		tracker.add([]types.StackEvent{stackEvent})
+
+		if tracker.summary() != "1 of 3 resources complete, 2 failed" {
+			t.Error(tracker.summary())
+		}
+	})
+}
+
+func TestFormatStackEvent(t *testing.T) {
+	event := stackEvent("1", "Database", "AWS::RDS::DBInstance", types.ResourceStatusCreateFailed, "DB instance class db.t9.micro is not supported", 3)
+	actual := formatStackEvent(event)
+
+	if !strings.Contains(actual, "CREATE_FAILED"+colorReset+"  Database (AWS::RDS::DBInstance) - DB instance class db.t9.micro is not supported") {
+		t.Error(actual)
+	}
+}
+
+func TestSignetDeployInvalidTimeout(t *testing.T) {
+	actual := callSignetDeploy([]string{"--timeout", "0s"})
+	expected := "Error: --timeout must be a positive duration, ex. 30m"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestDescribeStackDrift(t *testing.T) {
+	t.Run("not checked", func(t *testing.T) {
+		if !strings.HasPrefix(describeStackDrift(nil), "not checked") {
+			t.Error(describeStackDrift(nil))
+		}
+	})
+
+	t.Run("drifted", func(t *testing.T) {
+		drift := &types.StackDriftInformation{
+			StackDriftStatus:   types.StackDriftStatusDrifted,
+			LastCheckTimestamp: aws.Time(time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)),
+		}
+
+		actual := describeStackDrift(drift)
+		if !strings.Contains(actual, "DRIFTED") || !strings.Contains(actual, "--detect-drift") || !strings.HasSuffix(actual, "(checked 2023-07-01T12:00:00Z)") {
+			t.Error(actual)
+		}
+	})
+}
+
+func TestDescribeServiceHealth(t *testing.T) {
+	primary := func(state ecstypes.DeploymentRolloutState, running int32) []ecstypes.Deployment {
+		return []ecstypes.Deployment{{Status: aws.String("PRIMARY"), RolloutState: state, RunningCount: running, DesiredCount: 2, RolloutStateReason: aws.String("tasks failed to start")}}
+	}
+
+	cases := []struct {
+		name     string
+		service  ecstypes.Service
+		expected string
+	}{
+		{"healthy", ecstypes.Service{RunningCount: 2, DesiredCount: 2, Deployments: primary(ecstypes.DeploymentRolloutStateCompleted, 2)}, "healthy"},
+		{"rolling out", ecstypes.Service{RunningCount: 2, DesiredCount: 2, Deployments: primary(ecstypes.DeploymentRolloutStateInProgress, 1)}, "rolling out" + colorReset + " - 1 of 2 tasks"},
+		{"failed rollout", ecstypes.Service{RunningCount: 2, DesiredCount: 2, Deployments: primary(ecstypes.DeploymentRolloutStateFailed, 0)}, "the latest rollout failed: tasks failed to start"},
+		{"no tasks", ecstypes.Service{RunningCount: 0, DesiredCount: 2}, "no broker tasks are running"},
+		{"degraded", ecstypes.Service{RunningCount: 1, DesiredCount: 2}, "degraded" + colorReset + " - 1 of 2 tasks are running"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !strings.Contains(describeServiceHealth(c.service), c.expected) {
+				t.Error(describeServiceHealth(c.service))
+			}
+		})
+	}
+}
+
+func TestWriteBrokerURLToConfig(t *testing.T) {
+	t.Run("creates the config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), ".signetrc.yaml")
+
+		err := writeBrokerURLToConfig(configPath, "http://signet.example.com")
+		configBytes, _ := os.ReadFile(configPath)
+		if err != nil || string(configBytes) != "broker-url: http://signet.example.com\n" {
+			t.Error(err, string(configBytes))
+		}
+	})
+
+	t.Run("replaces broker-url and keeps the other settings", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), ".signetrc.yaml")
+		os.WriteFile(configPath, []byte("# staging broker\nbroker-url: http://localhost:3000\npublish:\n  name: user_service\n"), 0644)
+
+		err := writeBrokerURLToConfig(configPath, "http://signet.example.com")
+		configBytes, _ := os.ReadFile(configPath)
+		if err != nil || string(configBytes) != "# staging broker\nbroker-url: http://signet.example.com\npublish:\n  name: user_service\n" {
+			t.Error(err, string(configBytes))
+		}
+	})
+
+	t.Run("adds broker-url to an existing config", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), ".signetrc.yaml")
+		os.WriteFile(configPath, []byte("publish:\n  name: user_service\n"), 0644)
+
+		writeBrokerURLToConfig(configPath, "http://signet.example.com")
+		configBytes, _ := os.ReadFile(configPath)
+
+		var configMap map[string]interface{}
+		yaml.Unmarshal(configBytes, &configMap)
+		if configMap["broker-url"] != "http://signet.example.com" || configMap["publish"] == nil {
+			t.Error(string(configBytes))
+		}
+	})
+}
+
+func TestStackOutput(t *testing.T) {
+	stack := types.Stack{Outputs: []types.Output{{OutputKey: aws.String("ClusterName"), OutputValue: aws.String("signetbroker")}}}
+
+	if stackOutput(stack, "ClusterName") != "signetbroker" || stackOutput(stack, "ServiceName") != "" {
+		t.Error()
+	}
+}
+
+func TestCheckStackAbsent(t *testing.T) {
+	stackName = "signet-staging"
+	defer teardown()
+
+	t.Run("a missing stack can be previewed", func(t *testing.T) {
+		if err := checkStackAbsent(stackNotFoundError{stackName}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("an existing stack is an error", func(t *testing.T) {
+		err := checkStackAbsent(nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "stack signet-staging already exists") {
+			t.Error(err)
+		}
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		describeErr := errors.New("unable to describe CloudFormation stack signet-staging: AccessDenied")
+		if err := checkStackAbsent(describeErr); err != describeErr {
+			t.Error(err)
+		}
+	})
+}
+
+func TestIsStackNotFound(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"missing stacks":          {&smithy.GenericAPIError{Code: "ValidationError", Message: "Stack with id signet-staging does not exist"}, true},
+		"other validation errors": {&smithy.GenericAPIError{Code: "ValidationError", Message: "1 validation error detected"}, false},
+		"other API errors":        {&smithy.GenericAPIError{Code: "AccessDenied", Message: "User is not authorized, the role does not exist"}, false},
+		"network errors":          {errors.New("dial tcp: lookup cloudformation.us-east-1.amazonaws.com: no such host"), false},
+	}
+
+	for name, c := range cases {
+		if isStackNotFound(c.err) != c.expected {
+			t.Error(name)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+var upgrade bool
+var assumeYes bool
+
+/*
+upgradeStack updates an existing broker stack through a change set, so that the
+planned changes can be reviewed before anything is modified. Parameters that
+are not given keep the value the stack was deployed with.
+*/
+func upgradeStack(cmd *cobra.Command, cfClient *cloudformation.Client, template string, params map[string]string) error {
+	stack, err := describeStack(cfClient, stackName)
+	if err != nil {
+		return err
+	}
+
+	if !isUpgradableStatus(stack.StackStatus) {
+		return errors.New("stack " + stackName + " cannot be upgraded while its status is " + string(stack.StackStatus))
+	}
+
+	templateParams, err := utils.LoadTemplateParameters(template)
+	if err != nil {
+		return err
+	}
+
+	cfParams, allParams := upgradeParameters(params, stack.Parameters, templateParams)
+	err = validateStackParameters(allParams, template)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	changeSetName := "signet-upgrade-" + time.Now().UTC().Format("20060102150405")
+	changeSet, err := createChangeSet(cfClient, types.ChangeSetTypeUpdate, changeSetName, template, cfParams)
+	if err != nil {
+		return err
+	}
+
+	if changeSet.Status == types.ChangeSetStatusFailed {
+		deleteChangeSet(cfClient, changeSetName)
+
+		reason := aws.ToString(changeSet.StatusReason)
+		if isNoChangesReason(reason) {
+			cmd.Println(colorGreen + "Up to date" + colorReset + " - stack " + stackName + " already matches the template and parameters")
+			return nil
+		}
+		return errors.New("unable to plan the upgrade of stack " + stackName + ": " + reason)
+	}
+
+	cmd.Println("Planned changes to stack " + stackName + ":")
+	cmd.Println()
+	printChangeSet(cmd.OutOrStderr(), changeSet.Changes)
+	cmd.Println()
+	printDataLossWarnings(cmd.OutOrStderr(), changeSet.Changes)
+
+	if dryRun {
+		deleteChangeSet(cfClient, changeSetName)
+		cmd.Println(colorGreen + "Dry run" + colorReset + " - stack " + stackName + " was not changed")
+		return nil
+	}
+
+	err = confirmChangeSet(cmd.InOrStdin(), cmd.OutOrStderr())
+	if err != nil {
+		deleteChangeSet(cfClient, changeSetName)
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	tracker, err := startStackEventTracker(cfClient, aws.ToString(stack.StackId))
+	if err != nil {
+		return err
+	}
+
+	ecsInput := &cloudformation.ExecuteChangeSetInput{
+		StackName:       aws.String(stackName),
+		ChangeSetName:   aws.String(changeSetName),
+		DisableRollback: aws.Bool(false),
+	}
+	_, err = cfClient.ExecuteChangeSet(context.TODO(), ecsInput)
+	if err != nil {
+		return errors.New("unable to execute the change set of stack " + stackName + ": " + err.Error())
+	}
+
+	cmd.Println(colorGreen + "Upgrading" + colorReset + " - applying the changes to stack " + stackName + ", this will take a few minutes...")
+
+	if err := waitForUpgradeDone(cfClient, tracker); err != nil {
+		return err
+	}
+
+	cmd.Println("\n" + colorGreen + "Upgraded Successfully" + colorReset)
+
+	return nil
+}
+
+// stacks that are being changed, or whose last change failed to roll back, cannot be upgraded
+func isUpgradableStatus(status types.StackStatus) bool {
+	switch status {
+	case types.StackStatusCreateComplete, types.StackStatusUpdateComplete, types.StackStatusUpdateRollbackComplete, types.StackStatusImportComplete:
+		return true
+	}
+	return false
+}
+
+/*
+upgradeParameters returns the parameters of the change set, where template
+parameters that were not given use their previous value, and the combined
+values, which are validated together.
+*/
+func upgradeParameters(params map[string]string, previous []types.Parameter, templateParams map[string]utils.TemplateParameter) ([]types.Parameter, map[string]string) {
+	cfParams := toCloudFormationParameters(params)
+
+	allParams := map[string]string{}
+	for name, value := range params {
+		allParams[name] = value
+	}
+
+	for _, param := range previous {
+		name := aws.ToString(param.ParameterKey)
+		if _, given := params[name]; given {
+			continue
+		}
+		if _, inTemplate := templateParams[name]; !inTemplate {
+			continue
+		}
+
+		cfParams = append(cfParams, types.Parameter{
+			ParameterKey:     aws.String(name),
+			UsePreviousValue: aws.Bool(true),
+		})
+		allParams[name] = aws.ToString(param.ParameterValue)
+	}
+
+	return cfParams, allParams
+}
+
+// createChangeSet creates a change set of the stack, and waits until its changes have been planned
+func createChangeSet(cfClient *cloudformation.Client, changeSetType types.ChangeSetType, changeSetName, template string, cfParams []types.Parameter) (*cloudformation.DescribeChangeSetOutput, error) {
+	ccsInput := &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+		ChangeSetType: changeSetType,
+		TemplateBody:  aws.String(template),
+		Capabilities:  []types.Capability{"CAPABILITY_IAM"},
+		Tags: []types.Tag{
+			{
+				Key:   aws.String(stackTagKey),
+				Value: aws.String(stackTagValue),
+			},
+		},
+		Parameters: cfParams,
+	}
+
+	_, err := cfClient.CreateChangeSet(context.TODO(), ccsInput)
+	if err != nil {
+		return nil, errors.New("unable to create a change set for stack " + stackName + ": " + err.Error())
+	}
+
+	return waitForChangeSet(cfClient, changeSetName)
+}
+
+func waitForChangeSet(cfClient *cloudformation.Client, changeSetName string) (*cloudformation.DescribeChangeSetOutput, error) {
+	dcsInput := &cloudformation.DescribeChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	}
+
+	waiter := cloudformation.NewChangeSetCreateCompleteWaiter(cfClient)
+	// a change set without changes fails, which is checked by the caller rather than returned as an error
+	_ = waiter.Wait(context.TODO(), dcsInput, 5*time.Minute)
+
+	changeSet, err := cfClient.DescribeChangeSet(context.TODO(), dcsInput)
+	if err != nil {
+		return nil, errors.New("unable to describe the change set of stack " + stackName + ": " + err.Error())
+	}
+
+	if changeSet.Status != types.ChangeSetStatusCreateComplete && changeSet.Status != types.ChangeSetStatusFailed {
+		return nil, errors.New("timed out waiting for the change set of stack " + stackName + " to be created, its status is " + string(changeSet.Status))
+	}
+
+	// the changes are paginated, which only matters for unusually large templates
+	for nextToken := changeSet.NextToken; nextToken != nil; {
+		dcsInput.NextToken = nextToken
+		page, err := cfClient.DescribeChangeSet(context.TODO(), dcsInput)
+		if err != nil {
+			return nil, errors.New("unable to describe the change set of stack " + stackName + ": " + err.Error())
+		}
+		changeSet.Changes = append(changeSet.Changes, page.Changes...)
+		nextToken = page.NextToken
+	}
+
+	return changeSet, nil
+}
+
+func isNoChangesReason(reason string) bool {
+	return strings.Contains(reason, "didn't contain changes") || strings.Contains(reason, "No updates are to be performed")
+}
+
+func deleteChangeSet(cfClient *cloudformation.Client, changeSetName string) {
+	dcsInput := &cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	}
+	cfClient.DeleteChangeSet(context.TODO(), dcsInput)
+}
+
+func printChangeSet(out io.Writer, changes []types.Change) {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ACTION\tRESOURCE\tTYPE\tREPLACEMENT")
+	for _, change := range changes {
+		resource := change.ResourceChange
+		if resource == nil {
+			continue
+		}
+
+		replacement := string(resource.Replacement)
+		if len(replacement) == 0 {
+			replacement = "-"
+		}
+
+		fmt.Fprintln(writer, string(resource.Action)+"\t"+aws.ToString(resource.LogicalResourceId)+"\t"+aws.ToString(resource.ResourceType)+"\t"+replacement)
+	}
+	writer.Flush()
+}
+
+// an upgrade that is declined, or cannot be asked about (ex. in CI), fails rather than passing without upgrading
+func confirmChangeSet(in io.Reader, out io.Writer) error {
+	if assumeYes || confirm(in, out, "Execute these changes?") {
+		return nil
+	}
+	return errors.New("the upgrade of stack " + stackName + " was not confirmed, so the change set was deleted and the stack was not changed - pass --yes to execute the change set without asking")
+}
+
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprint(out, question+" [y/N] ")
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+/*
+waitForUpgradeDone waits until the update, or the rollback of a failed update,
+has finished. CloudFormation rolls a failed update back to the previous
+template and parameters, so the broker keeps running its previous version.
+It watches the stack's events rather than using NewStackUpdateCompleteWaiter,
+which can only report the final status, so that progress and the resource
+that failed are shown, and --timeout applies.
+*/
+func waitForUpgradeDone(cfClient *cloudformation.Client, tracker *stackEventTracker) error {
+	stack, err := watchStack(cfClient, tracker, deployTimeout)
+	if err != nil {
+		return err
+	}
+
+	switch stack.StackStatus {
+	case types.StackStatusUpdateComplete:
+		return nil
+	case types.StackStatusUpdateRollbackComplete:
+		return errors.New(describeStackFailure("upgrade", stack, tracker) + " - the stack was rolled back, so the broker is unchanged")
+	case types.StackStatusUpdateRollbackFailed:
+		return errors.New(describeStackFailure("upgrade", stack, tracker) + " - the rollback failed too, continue it from the AWS console, or with `aws cloudformation continue-update-rollback --stack-name " + stackName + "`")
+	}
+
+	return errors.New(describeStackFailure("upgrade", stack, tracker))
+}
+
+func init() {
+	deployCmd.Flags().BoolVar(&upgrade, "upgrade", false, "upgrade an existing broker stack through a change set (optional)")
+	deployCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "execute the change set of --upgrade without asking for confirmation (optional)")
+
+	viper.BindPFlag("deploy.upgrade", deployCmd.Flags().Lookup("upgrade"))
+	viper.BindPFlag("deploy.yes", deployCmd.Flags().Lookup("yes"))
+}
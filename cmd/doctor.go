@@ -0,0 +1,357 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	client "github.com/signet-framework/signet-cli/client"
+)
+
+const configFileName = ".signetrc.yaml"
+
+// abstract external commands and AWS to enable mocking during testing
+var runCommand = func(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).Output()
+	return strings.TrimSpace(string(output)), err
+}
+var checkAWSIdentity = getAWSIdentity
+
+type doctorCheck struct {
+	name    string
+	passed  bool
+	skipped bool
+	detail  string
+	hint    string
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "check that the tools and configuration Signet depends on are set up",
+	Long: `check that the tools and configuration Signet depends on are set up, and print a checklist with hints for fixing anything that is not
+
+	checks:
+
+	node, npm, npx      needed to run mountebank (proxy) and dredd (test)
+
+	signet-cli package  the global signet-cli npm package, with mountebank and dredd installed
+
+	git                 used to default --version and --branch to the current commit and branch
+
+	.signetrc.yaml      that the config file in this directory (if any) is valid, unless --ignore-config is set
+
+	broker              that the broker at --broker-url is reachable
+
+	AWS                 that the AWS credentials are accepted, for deploy and undeploy (skipped when no credentials or region are configured)
+
+	flags:
+
+	-u --broker-url     the scheme, domain, and port where the Signet broker is being hosted (optional, the broker check is skipped without it)
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checks := []doctorCheck{}
+		checks = append(checks, checkToolVersion("node", "install Node.js from https://nodejs.org"))
+		checks = append(checks, checkToolVersion("npm", "npm is installed with Node.js, see https://nodejs.org"))
+		checks = append(checks, checkToolVersion("npx", "npx is installed with npm 7 or later, upgrade with `npm install -g npm`"))
+		checks = append(checks, checkSignetPackage()...)
+		checks = append(checks, checkGit()...)
+		checks = append(checks, checkConfigFile())
+		checks = append(checks, checkBroker(brokerURL))
+		checks = append(checks, checkAWS())
+
+		cmd.Println("Signet doctor")
+		cmd.Println()
+
+		failed := 0
+		for _, check := range checks {
+			status := colorGreen + "PASS" + colorReset
+			if check.skipped {
+				status = "SKIP"
+			} else if !check.passed {
+				status = colorRed + "FAIL" + colorReset
+				failed++
+			}
+
+			cmd.Println("  " + status + "  " + check.name + describeCheckDetail(check.detail))
+			if len(check.hint) != 0 && (!check.passed || check.skipped) {
+				cmd.Println("        " + check.hint)
+			}
+		}
+		cmd.Println()
+
+		if failed != 0 {
+			cmd.SilenceUsage = true
+			return errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(len(checks)) + " checks failed")
+		}
+
+		cmd.Println(colorGreen + "Success" + colorReset + " - Signet is ready to use")
+		return nil
+	},
+}
+
+func describeCheckDetail(detail string) string {
+	if len(detail) == 0 {
+		return ""
+	}
+	return " - " + detail
+}
+
+func checkToolVersion(tool, hint string) doctorCheck {
+	check := doctorCheck{name: tool, hint: hint}
+
+	toolVersion, err := runCommand(tool, "--version")
+	if err != nil {
+		check.detail = tool + " was not found on PATH"
+		return check
+	}
+
+	check.passed = true
+	check.detail = toolVersion
+	return check
+}
+
+// the signet-cli npm package provides mountebank for proxy and dredd for test
+func checkSignetPackage() []doctorCheck {
+	packageCheck := doctorCheck{name: "signet-cli npm package", hint: "install it with `npm install -g signet-cli`"}
+
+	signetRoot, err := getNpmPkgRoot()
+	if err == nil {
+		_, err = os.Stat(signetRoot)
+	}
+	if err != nil {
+		packageCheck.detail = "not found in the global npm packages"
+		skipped := doctorCheck{name: "mountebank and dredd", skipped: true, detail: "the signet-cli npm package is not installed"}
+		return []doctorCheck{packageCheck, skipped}
+	}
+
+	packageCheck.passed = true
+	packageCheck.detail = describePackage(signetRoot)
+
+	checks := []doctorCheck{packageCheck}
+	for _, dependency := range []string{"mountebank", "dredd"} {
+		check := doctorCheck{name: dependency, hint: "reinstall the signet-cli npm package with `npm install -g signet-cli`"}
+
+		dependencyRoot := signetRoot + "/node_modules/" + dependency
+		if _, err := os.Stat(dependencyRoot); err != nil {
+			check.detail = "not installed in " + signetRoot
+		} else {
+			check.passed = true
+			check.detail = describePackage(dependencyRoot)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func describePackage(packageRoot string) string {
+	packageJSON, err := os.ReadFile(packageRoot + "/package.json")
+	if err != nil {
+		return packageRoot
+	}
+
+	var pkg struct {
+		Version string `json:"version"`
+	}
+	if json.Unmarshal(packageJSON, &pkg) != nil || len(pkg.Version) == 0 {
+		return packageRoot
+	}
+
+	return "version " + pkg.Version + " in " + packageRoot
+}
+
+func checkGit() []doctorCheck {
+	gitCheck := checkToolVersion("git", "install git from https://git-scm.com")
+	repoCheck := doctorCheck{name: "git repository", hint: "run signet from a git repository, or always pass --version and --branch"}
+
+	if !gitCheck.passed {
+		repoCheck.skipped = true
+		repoCheck.detail = "git is not installed"
+		return []doctorCheck{gitCheck, repoCheck}
+	}
+
+	sha, err := runCommand("git", "rev-parse", "--short=10", "HEAD")
+	if err != nil {
+		repoCheck.detail = "this directory is not a git repository with at least one commit, so --version and --branch cannot default to the current commit and branch"
+		return []doctorCheck{gitCheck, repoCheck}
+	}
+
+	currentBranch, _ := runCommand("git", "branch", "--show-current")
+	if len(currentBranch) == 0 {
+		currentBranch = "detached HEAD"
+	}
+
+	repoCheck.passed = true
+	repoCheck.detail = "commit " + sha + " on " + currentBranch
+
+	changes, err := runCommand("git", "status", "--porcelain")
+	if err == nil && len(changes) != 0 {
+		repoCheck.detail += ", with uncommitted changes that are not part of the default --version"
+	}
+
+	return []doctorCheck{gitCheck, repoCheck}
+}
+
+/*
+checkConfigFile parses .signetrc.yaml, and checks that its top level keys are
+broker-url or the name of a command, and that command settings are maps. The
+check is skipped with --ignore-config, since the file is not read.
+*/
+func checkConfigFile() doctorCheck {
+	check := doctorCheck{name: configFileName}
+
+	if IgnoreConfig {
+		check.skipped = true
+		check.detail = "ignored because of --ignore-config"
+		return check
+	}
+
+	configBytes, err := os.ReadFile(configFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		check.skipped = true
+		check.detail = "no config file in this directory"
+		return check
+	}
+	if err != nil {
+		check.detail = err.Error()
+		return check
+	}
+
+	var configMap map[string]interface{}
+	err = yaml.Unmarshal(configBytes, &configMap)
+	if err != nil {
+		check.detail = "invalid YAML: " + err.Error()
+		check.hint = "fix the YAML syntax, or move the file aside and run signet with flags"
+		return check
+	}
+
+	commandNames := map[string]bool{}
+	for _, command := range RootCmd.Commands() {
+		commandNames[command.Name()] = true
+	}
+
+	problems := []string{}
+	for key, value := range configMap {
+		switch {
+		case key == "broker-url":
+			if _, ok := value.(string); !ok {
+				problems = append(problems, "broker-url must be a URL")
+			}
+		case !commandNames[key]:
+			problems = append(problems, key+" is not a signet command")
+		default:
+			if _, ok := value.(map[interface{}]interface{}); !ok {
+				problems = append(problems, key+" must be a map of flag names to values")
+			}
+		}
+	}
+	sort.Strings(problems)
+
+	if len(problems) != 0 {
+		check.detail = strings.Join(problems, "; ")
+		check.hint = "the top level keys of .signetrc.yaml are broker-url and the names of signet commands, see the README for each command's settings"
+		return check
+	}
+
+	check.passed = true
+	check.detail = "valid"
+	return check
+}
+
+func checkBroker(brokerURL string) doctorCheck {
+	check := doctorCheck{name: "broker", hint: "pass --broker-url or set broker-url in .signetrc.yaml to check the broker"}
+
+	if len(brokerURL) == 0 {
+		check.skipped = true
+		check.detail = "no broker URL is configured"
+		return check
+	}
+
+	err := client.CheckBrokerReachable(brokerURL)
+	if err != nil {
+		check.detail = brokerURL + " is not reachable: " + err.Error()
+		check.hint = "check that the broker is running (see signet deploy), and that --broker-url includes the scheme and port"
+		return check
+	}
+
+	check.passed = true
+	check.detail = brokerURL + " is reachable"
+	return check
+}
+
+// AWS is only needed for deploy and undeploy, so the check is skipped rather than failed when it is not set up
+type awsNotConfiguredError struct {
+	reason string
+}
+
+func (e awsNotConfiguredError) Error() string {
+	return e.reason
+}
+
+func checkAWS() doctorCheck {
+	check := doctorCheck{name: "AWS credentials", hint: "only needed for deploy and undeploy - configure credentials with `aws configure sso` and log in with `aws sso login`, and set a region with `aws configure` or AWS_REGION"}
+
+	identity, err := checkAWSIdentity()
+	var notConfigured awsNotConfiguredError
+	if errors.As(err, &notConfigured) {
+		check.skipped = true
+		check.detail = err.Error()
+		return check
+	}
+	if err != nil {
+		check.detail = err.Error()
+		return check
+	}
+
+	check.passed = true
+	check.detail = identity
+	return check
+}
+
+/*
+getAWSIdentity checks that the credentials work by asking STS who they belong
+to. Missing credentials or a missing region return an awsNotConfiguredError,
+while credentials that AWS rejects return any other error.
+*/
+func getAWSIdentity() (string, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", errors.New("unable to load AWS config: " + err.Error())
+	}
+
+	if len(cfg.Region) == 0 {
+		return "", awsNotConfiguredError{"no AWS region is configured"}
+	}
+
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return "", awsNotConfiguredError{"no AWS credentials are configured"}
+	}
+
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", errors.New("AWS rejected the configured credentials: " + err.Error())
+	}
+
+	return "account " + *identity.Account + " in " + cfg.Region, nil
+}
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+}
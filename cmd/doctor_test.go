@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/* ------------- helpers ------------- */
+
+func callSignetDoctor(argsAndFlags []string) actualOut {
+	actual := new(bytes.Buffer)
+	RootCmd.SetOut(actual)
+	RootCmd.SetErr(actual)
+	RootCmd.SetArgs(append([]string{"doctor"}, argsAndFlags...))
+	RootCmd.Execute()
+	return actualOut{actual.String()}
+}
+
+// mocks a machine where every tool is installed, except the ones in missingTools
+func mockDoctorEnvironment(t *testing.T, missingTools ...string) func() {
+	realRunCommand := runCommand
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realCheckAWSIdentity := checkAWSIdentity
+
+	runCommand = func(name string, args ...string) (string, error) {
+		for _, tool := range missingTools {
+			if name == tool {
+				return "", errors.New("executable file not found in $PATH")
+			}
+		}
+		if name == "git" && args[0] == "rev-parse" {
+			return "abc1234567", nil
+		}
+		if name == "git" && args[0] == "branch" {
+			return "main", nil
+		}
+		if name == "git" && args[0] == "status" {
+			return "", nil
+		}
+		return name + " 1.0.0", nil
+	}
+
+	signetRoot := t.TempDir()
+	for _, dependency := range []string{"mountebank", "dredd"} {
+		dependencyRoot := filepath.Join(signetRoot, "node_modules", dependency)
+		os.MkdirAll(dependencyRoot, 0755)
+		os.WriteFile(filepath.Join(dependencyRoot, "package.json"), []byte(`{"version": "2.8.0"}`), 0644)
+	}
+	getNpmPkgRoot = func() (string, error) { return signetRoot, nil }
+
+	checkAWSIdentity = func() (string, error) { return "account 123456789012 in us-east-1", nil }
+
+	return func() {
+		runCommand = realRunCommand
+		getNpmPkgRoot = realGetNpmPkgRoot
+		checkAWSIdentity = realCheckAWSIdentity
+	}
+}
+
+func mockBrokerParticipants(t *testing.T, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/participants" {
+			t.Error(r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`[]`))
+	}))
+}
+
+/* ------------- tests ------------- */
+
+func TestSignetDoctorAllChecksPass(t *testing.T) {
+	restore := mockDoctorEnvironment(t)
+	defer restore()
+
+	server := mockBrokerParticipants(t, 200)
+	defer server.Close()
+
+	actual := callSignetDoctor([]string{"--broker-url", server.URL})
+
+	t.Run("reports the broker as reachable", func(t *testing.T) {
+		actual.contains("PASS"+colorReset+"  broker - "+server.URL+" is reachable\n", t)
+	})
+
+	t.Run("reports the dependency versions", func(t *testing.T) {
+		actual.contains("mountebank - version 2.8.0", t)
+		actual.contains("git repository - commit abc1234567 on main", t)
+	})
+
+	t.Run("succeeds", func(t *testing.T) {
+		actual.contains("Signet is ready to use", t)
+	})
+
+	teardown()
+}
+
+func TestSignetDoctorReportsFailures(t *testing.T) {
+	restore := mockDoctorEnvironment(t, "npx")
+	defer restore()
+
+	server := mockBrokerParticipants(t, 500)
+	defer server.Close()
+
+	actual := callSignetDoctor([]string{"--broker-url", server.URL})
+
+	t.Run("missing tools have a hint", func(t *testing.T) {
+		actual.contains("npx - npx was not found on PATH", t)
+		actual.contains("upgrade with `npm install -g npm`", t)
+	})
+
+	t.Run("broker errors fail", func(t *testing.T) {
+		actual.contains(server.URL+" is not reachable: the broker responded with status 500", t)
+	})
+
+	t.Run("returns an error", func(t *testing.T) {
+		actual.contains("Error: 2 of 11 checks failed", t)
+	})
+
+	teardown()
+}
+
+func TestSignetDoctorSkipsBrokerWithoutURL(t *testing.T) {
+	restore := mockDoctorEnvironment(t)
+	defer restore()
+
+	actual := callSignetDoctor([]string{})
+	actual.contains("SKIP  broker - no broker URL is configured", t)
+
+	teardown()
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+
+	t.Run("unknown keys fail", func(t *testing.T) {
+		os.WriteFile(configFileName, []byte("broker-url: http://localhost:3000\ntests:\n  name: user_service\n"), 0644)
+
+		check := checkConfigFile()
+		if check.passed || check.detail != "tests is not a signet command" {
+			t.Error(check)
+		}
+	})
+
+	t.Run("invalid YAML fails", func(t *testing.T) {
+		os.WriteFile(configFileName, []byte("test:\n  name: [user_service\n"), 0644)
+
+		check := checkConfigFile()
+		if check.passed {
+			t.Error(check)
+		}
+	})
+
+	t.Run("command settings pass", func(t *testing.T) {
+		os.WriteFile(configFileName, []byte("broker-url: http://localhost:3000\ntest:\n  name: user_service\n"), 0644)
+
+		check := checkConfigFile()
+		if !check.passed {
+			t.Error(check)
+		}
+	})
+
+	t.Run("skipped with --ignore-config", func(t *testing.T) {
+		defer func() { IgnoreConfig = false }()
+		IgnoreConfig = true
+		os.WriteFile(configFileName, []byte("tests:\n  name: [user_service\n"), 0644)
+
+		check := checkConfigFile()
+		if !check.skipped || check.detail != "ignored because of --ignore-config" {
+			t.Error(check)
+		}
+	})
+}
+
+func TestSignetDoctorSkipsAWSWithoutCredentials(t *testing.T) {
+	restore := mockDoctorEnvironment(t)
+	defer restore()
+
+	checkAWSIdentity = func() (string, error) { return "", awsNotConfiguredError{"no AWS credentials are configured"} }
+
+	actual := callSignetDoctor([]string{})
+
+	t.Run("reports the AWS check as skipped", func(t *testing.T) {
+		actual.contains("SKIP  AWS credentials - no AWS credentials are configured", t)
+	})
+
+	t.Run("succeeds", func(t *testing.T) {
+		actual.contains("Signet is ready to use", t)
+	})
+
+	teardown()
+}
+
+func TestSignetDoctorFailsRejectedAWSCredentials(t *testing.T) {
+	restore := mockDoctorEnvironment(t)
+	defer restore()
+
+	checkAWSIdentity = func() (string, error) {
+		return "", errors.New("AWS rejected the configured credentials: ExpiredToken")
+	}
+
+	actual := callSignetDoctor([]string{})
+	actual.contains("Error: 1 of 11 checks failed", t)
+
+	teardown()
+}
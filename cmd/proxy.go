@@ -3,21 +3,29 @@ package cmd
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	client "github.com/signet-framework/signet-cli/client"
 	utils "github.com/signet-framework/signet-cli/utils"
 )
 
 var port string
 var target string
 var providerName string
+var adminPort string
+var tlsOpts proxyTLSOptions
+var routes []utils.ProxyRoute
+var validateGraphQL bool
 
 var proxyCmd = &cobra.Command{
 	Use:   "proxy",
@@ -36,7 +44,33 @@ var proxyCmd = &cobra.Command{
 
 	-m --provider-name  the canonical name of the provider service that the mock or stub represents
 
+	-a --admin-port     the port for the proxy control API used by test harnesses (optional)
+
+	--tls-cert          path to a PEM certificate, makes signet proxy listen on HTTPS (optional, requires --tls-key)
+
+	--tls-key           path to the PEM private key for --tls-cert (optional)
+
+	--target-ca         path to a PEM CA certificate used to verify an HTTPS --target (optional)
+
+	--insecure-skip-verify  skip verification of the HTTPS --target's certificate (optional)
+
+	--target-cert       path to a PEM client certificate presented to the target for mTLS (optional, requires --target-key)
+
+	--target-key        path to the PEM private key for --target-cert (optional)
+
+	-g --validate-graphql  validate recorded GraphQL operations against the provider's schema published to the broker (optional)
+
+	-u --broker-url     the scheme, domain, and port where the Signet broker is being hosted (only for --validate-graphql)
+
+	--work-dir          the directory where a temporary directory is made for the mountebank config and data (optional, defaults to the system temp directory)
+
+	--keep-artifacts    do not remove the temporary directory when the proxy stops (optional)
+
 	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+
+	to record traffic for several providers at once, replace --target, --path and --provider-name with a
+	proxy.routes list in .signetrc.yaml. Each route matches requests by path-prefix and/or host, and gets
+	its own target, provider-name, and contract path.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path = viper.GetString("proxy.path")
@@ -44,22 +78,70 @@ var proxyCmd = &cobra.Command{
 		target = viper.GetString("proxy.target")
 		name = viper.GetString("proxy.name")
 		providerName = viper.GetString("proxy.provider-name")
+		adminPort = viper.GetString("proxy.admin-port")
+		validateGraphQL = viper.GetBool("proxy.validate-graphql")
+		workDir = viper.GetString("proxy.work-dir")
+		keepArtifacts = viper.GetBool("proxy.keep-artifacts")
+		tlsOpts = proxyTLSOptions{
+			certPath:           viper.GetString("proxy.tls-cert"),
+			keyPath:            viper.GetString("proxy.tls-key"),
+			targetCAPath:       viper.GetString("proxy.target-ca"),
+			targetCertPath:     viper.GetString("proxy.target-cert"),
+			targetKeyPath:      viper.GetString("proxy.target-key"),
+			insecureSkipVerify: viper.GetBool("proxy.insecure-skip-verify"),
+		}
+
+		err := viper.UnmarshalKey("proxy.routes", &routes)
+		if err != nil {
+			return errors.New("failed to read proxy.routes from config file: " + err.Error())
+		}
+
+		err = validateProxyFlags(path, port, target, name, providerName, routes)
+		if err != nil {
+			return err
+		}
+
+		if len(routes) == 0 {
+			routes = []utils.ProxyRoute{{Target: target, ProviderName: providerName, Path: path}}
+		}
+
+		if validateGraphQL && len(brokerURL) == 0 {
+			return errors.New("No --broker-url was provided. This flag is required for --validate-graphql.")
+		}
+
+		err = validateProxyTLSFlags(tlsOpts)
+		if err != nil {
+			return err
+		}
+
+		tlsMaterial, err := loadProxyTLSMaterial(tlsOpts)
+		if err != nil {
+			return err
+		}
 
-		err := validateProxyFlags(path, port, target, name, providerName)
+		mbRoutes, stopForwarders, err := startTargetForwarders(routes, tlsOpts, cmd.ErrOrStderr())
 		if err != nil {
 			return err
 		}
+		defer stopForwarders()
 
 		signetRoot, err := getNpmPkgRoot()
 		if err != nil {
 			return err
 		}
 		mbPath := signetRoot + "/node_modules/mountebank"
-		configPath := signetRoot + "/config.ejs"
-		dataDir := signetRoot + "/mbdata"
+
+		proxyDir, cleanupWorkDir, err := createWorkDir(workDir, "signet-proxy-")
+		if err != nil {
+			return err
+		}
+		defer cleanupWorkDir()
+
+		configPath := proxyDir + "/config.ejs"
+		dataDir := proxyDir + "/mbdata"
 		stubsDir := dataDir + "/" + port + "/stubs"
 
-		err = setupMbConfig(port, target, configPath)
+		err = setupMbConfig(port, configPath, mbRoutes, tlsMaterial)
 		if err != nil {
 			return err
 		}
@@ -70,38 +152,182 @@ var proxyCmd = &cobra.Command{
 			return errors.New("failed to start mountebank: " + err.Error())
 		}
 
-		cmd.Println(colorGreen + "Listening" + colorReset + " - Signet proxy is listening on port " + port + " and will proxy messages for " + target)
+		mbDone := make(chan error, 1)
+		go func() {
+			mbDone <- mbCmd.Wait()
+		}()
+
+		admin := newProxyAdmin(stubsDir, name, routes)
+		if len(adminPort) != 0 {
+			listener, err := net.Listen("tcp", ":"+adminPort)
+			if err != nil {
+				mbCmd.Process.Kill()
+				return errors.New("failed to start proxy control API: " + err.Error())
+			}
+			go http.Serve(listener, admin.handler())
+		}
+
+		for _, route := range routes {
+			cmd.Println(colorGreen + "Listening" + colorReset + " - Signet proxy is listening on port " + port + " and will proxy messages" + describeRoute(route) + " for " + route.Target)
+		}
+		if len(adminPort) != 0 {
+			cmd.Println("Proxy control API is listening on port " + adminPort)
+		}
 		cmd.Println("\nHit Ctl + C to stop")
 
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt)
-		go func() {
-			for range c {
-				cmd.Println("\n\ngenerating consumer contract...")
-
-				err, ok := utils.CreatePact(stubsDir, path, name, providerName)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				if ok {
-					cmd.Println("\n" + colorGreen + "Success" + colorReset + " - Signet proxy wrote the consumer contract to " + path)
-				} else {
-					cmd.Println("\nInfo - No contract was generated because Signet proxy did not record any interactions")
-				}
+		// Ctrl + C is handled here rather than in a goroutine, so that the contracts are
+		// written before mountebank's recordings are removed with the work directory
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		select {
+		case <-interrupt:
+			err = writeRecordedContracts(cmd, stubsDir, routes)
+			stopMountebank(mbCmd)
+			<-mbDone
+			return err
+		case err = <-mbDone:
+			if exitedOnInterrupt(interrupt, err) {
+				return writeRecordedContracts(cmd, stubsDir, routes)
 			}
-		}()
 
-		err = mbCmd.Wait()
-		if err != nil {
-			return errors.New("mountebank exited early: " + err.Error())
+			if err != nil {
+				return errors.New("mountebank exited early: " + err.Error())
+			}
+		case <-admin.shutdown:
+			stopMountebank(mbCmd)
+			<-mbDone
+			cmd.Println("\nSignet proxy was shut down through the control API")
 		}
 
 		return nil
 	},
 }
 
-func validateProxyFlags(path, port, target, name, providerName string) error {
+func writeRecordedContracts(cmd *cobra.Command, stubsDir string, routes []utils.ProxyRoute) error {
+	cmd.Println("\n\ngenerating consumer contract...")
+
+	writtenPaths, warnings, err := writeProxyContracts(stubsDir, name, routes)
+	if err != nil {
+		return err
+	}
+
+	for _, writtenPath := range writtenPaths {
+		cmd.Println("\n" + colorGreen + "Success" + colorReset + " - Signet proxy wrote the consumer contract to " + writtenPath)
+	}
+
+	for _, warning := range warnings {
+		cmd.Println(colorRed + "Warning" + colorReset + " - " + warning)
+	}
+
+	if len(writtenPaths) == 0 {
+		cmd.Println("\nInfo - No contract was generated because Signet proxy did not record any interactions")
+	}
+
+	return nil
+}
+
+// how long to wait for Ctrl + C after mountebank exits, before treating the exit as unexpected
+var interruptGracePeriod = time.Second
+
+/*
+exitedOnInterrupt reports whether mountebank exited because of Ctrl + C. The
+terminal sends it to mountebank as well, which can exit before the signal
+reaches the proxy, so the signal is waited for briefly unless mountebank was
+itself terminated by SIGINT.
+*/
+func exitedOnInterrupt(interrupt <-chan os.Signal, exitErr error) bool {
+	var exitError *exec.ExitError
+	if errors.As(exitErr, &exitError) {
+		status, ok := exitError.Sys().(syscall.WaitStatus)
+		if ok && status.Signaled() && status.Signal() == syscall.SIGINT {
+			return true
+		}
+	}
+
+	select {
+	case <-interrupt:
+		return true
+	case <-time.After(interruptGracePeriod):
+		return false
+	}
+}
+
+func stopMountebank(mbCmd *exec.Cmd) {
+	// os.Interrupt is not supported on windows, fall back to killing the process
+	if err := mbCmd.Process.Signal(os.Interrupt); err != nil {
+		mbCmd.Process.Kill()
+	}
+}
+
+/*
+writes a consumer contract for every route that recorded interactions. Match
+files which could not be decoded are skipped and returned as warnings. With
+--validate-graphql, the GraphQL operations in each contract are checked against
+the schema the provider published to the broker, and any problems are returned
+as warnings.
+*/
+func writeProxyContracts(stubsDir, consumerName string, routes []utils.ProxyRoute) ([]string, []string, error) {
+	writtenPaths, skipped, err := utils.CreatePacts(stubsDir, consumerName, routes)
+
+	warnings := []string{}
+	for _, skippedErr := range skipped {
+		warnings = append(warnings, skippedErr.Error())
+	}
+
+	if err != nil || !validateGraphQL {
+		return writtenPaths, warnings, err
+	}
+
+	for _, route := range routes {
+		if !containsString(writtenPaths, route.Path) {
+			continue
+		}
+
+		spec, err := client.GetLatestSpec(brokerURL, route.ProviderName)
+		if err != nil {
+			return writtenPaths, warnings, err
+		}
+
+		problems, err := utils.ValidateGraphQLContract(route.Path, utils.GraphQLSchemaFromSpec(spec))
+		if err != nil {
+			return writtenPaths, warnings, err
+		}
+
+		for _, problem := range problems {
+			warnings = append(warnings, route.ProviderName+": "+problem)
+		}
+	}
+
+	return writtenPaths, warnings, nil
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func describeRoute(route utils.ProxyRoute) string {
+	description := ""
+	if len(route.Host) != 0 {
+		description += " with host " + route.Host
+	}
+	if len(route.PathPrefix) != 0 {
+		description += " under " + route.PathPrefix
+	}
+	return description
+}
+
+func validateProxyFlags(path, port, target, name, providerName string, routes []utils.ProxyRoute) error {
+	if len(routes) != 0 {
+		return validateProxyRoutesFlags(path, port, target, name, providerName, routes)
+	}
+
 	if len(path) == 0 {
 		return errors.New("No --path was provided. This is a required flag.")
 	}
@@ -125,28 +351,62 @@ func validateProxyFlags(path, port, target, name, providerName string) error {
 	return nil
 }
 
-func setupMbConfig(port, target, configPath string) error {
+func validateProxyRoutesFlags(path, port, target, name, providerName string, routes []utils.ProxyRoute) error {
+	if len(port) == 0 {
+		return errors.New("No --port was provided. This is a required flag.")
+	}
+
+	if len(target) != 0 {
+		return errors.New("--target cannot be used together with proxy.routes, set a target for each route instead")
+	}
+
+	if len(path) != 0 {
+		return errors.New("--path cannot be used together with proxy.routes, set a path for each route instead")
+	}
+
+	if len(providerName) != 0 {
+		return errors.New("--provider-name cannot be used together with proxy.routes, set a provider-name for each route instead")
+	}
+
+	if len(name) == 0 {
+		return errors.New("No --name was provided. This is a required flag.")
+	}
+
+	return utils.ValidateProxyRoutes(routes)
+}
+
+func setupMbConfig(port, configPath string, routes []utils.ProxyRoute, tlsMaterial proxyTLSMaterial) error {
 	portInt, err := strconv.Atoi(port)
 	if err != nil {
 		return err
 	}
 
+	protocol := "http"
+	if len(tlsMaterial.cert) != 0 {
+		protocol = "https"
+	}
+
 	proxyConfig := utils.ProxyConfig{
 		Port:     portInt,
 		Name:     "signet-proxy",
-		Protocol: "http",
-		Stubs: []utils.MbStub{
-			utils.MbStub{
-				Responses: []utils.MbResponse{
-					utils.MbResponse{
-						Proxy: utils.MbProxy{
-							To:   target,
-							Mode: "proxyOnce",
-						},
+		Protocol: protocol,
+		Key:      tlsMaterial.key,
+		Cert:     tlsMaterial.cert,
+		Stubs:    []utils.MbStub{},
+	}
+
+	for _, route := range routes {
+		proxyConfig.Stubs = append(proxyConfig.Stubs, utils.MbStub{
+			Predicates: route.Predicates(),
+			Responses: []utils.MbResponse{
+				utils.MbResponse{
+					Proxy: utils.MbProxy{
+						To:   route.Target,
+						Mode: "proxyOnce",
 					},
 				},
 			},
-		},
+		})
 	}
 
 	jsonBytes, err := json.Marshal(proxyConfig)
@@ -170,10 +430,30 @@ func init() {
 	proxyCmd.Flags().StringVarP(&target, "target", "t", "", "the URL of the running provider stub or mock")
 	proxyCmd.Flags().StringVarP(&name, "name", "n", "", "the canonical name of the consumer service")
 	proxyCmd.Flags().StringVarP(&providerName, "provider-name", "m", "", "the canonical name of the provider service that the mock or stub represents")
+	proxyCmd.Flags().StringVarP(&adminPort, "admin-port", "a", "", "the port for the proxy control API used by test harnesses (optional)")
+	proxyCmd.Flags().BoolVarP(&validateGraphQL, "validate-graphql", "g", false, "validate recorded GraphQL operations against the provider's schema published to the broker (optional)")
+	proxyCmd.Flags().StringVar(&workDir, "work-dir", "", "the directory where a temporary directory is made for the mountebank config and data (optional)")
+	proxyCmd.Flags().BoolVar(&keepArtifacts, "keep-artifacts", false, "do not remove the temporary directory when the proxy stops (optional)")
+	proxyCmd.Flags().StringVar(&tlsOpts.certPath, "tls-cert", "", "path to a PEM certificate, makes signet proxy listen on HTTPS (optional, requires --tls-key)")
+	proxyCmd.Flags().StringVar(&tlsOpts.keyPath, "tls-key", "", "path to the PEM private key for --tls-cert (optional)")
+	proxyCmd.Flags().StringVar(&tlsOpts.targetCAPath, "target-ca", "", "path to a PEM CA certificate used to verify an HTTPS --target (optional)")
+	proxyCmd.Flags().BoolVar(&tlsOpts.insecureSkipVerify, "insecure-skip-verify", false, "skip verification of the HTTPS --target's certificate (optional)")
+	proxyCmd.Flags().StringVar(&tlsOpts.targetCertPath, "target-cert", "", "path to a PEM client certificate presented to the target for mTLS (optional, requires --target-key)")
+	proxyCmd.Flags().StringVar(&tlsOpts.targetKeyPath, "target-key", "", "path to the PEM private key for --target-cert (optional)")
 
 	viper.BindPFlag("proxy.path", proxyCmd.Flags().Lookup("path"))
 	viper.BindPFlag("proxy.port", proxyCmd.Flags().Lookup("port"))
 	viper.BindPFlag("proxy.target", proxyCmd.Flags().Lookup("target"))
 	viper.BindPFlag("proxy.name", proxyCmd.Flags().Lookup("name"))
 	viper.BindPFlag("proxy.provider-name", proxyCmd.Flags().Lookup("provider-name"))
+	viper.BindPFlag("proxy.admin-port", proxyCmd.Flags().Lookup("admin-port"))
+	viper.BindPFlag("proxy.validate-graphql", proxyCmd.Flags().Lookup("validate-graphql"))
+	viper.BindPFlag("proxy.work-dir", proxyCmd.Flags().Lookup("work-dir"))
+	viper.BindPFlag("proxy.keep-artifacts", proxyCmd.Flags().Lookup("keep-artifacts"))
+	viper.BindPFlag("proxy.tls-cert", proxyCmd.Flags().Lookup("tls-cert"))
+	viper.BindPFlag("proxy.tls-key", proxyCmd.Flags().Lookup("tls-key"))
+	viper.BindPFlag("proxy.target-ca", proxyCmd.Flags().Lookup("target-ca"))
+	viper.BindPFlag("proxy.insecure-skip-verify", proxyCmd.Flags().Lookup("insecure-skip-verify"))
+	viper.BindPFlag("proxy.target-cert", proxyCmd.Flags().Lookup("target-cert"))
+	viper.BindPFlag("proxy.target-key", proxyCmd.Flags().Lookup("target-key"))
 }
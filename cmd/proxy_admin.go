@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/*
+proxyAdmin serves the control API that lets a test harness drive a running
+signet proxy. It lives on its own port so that it never collides with the
+traffic being recorded between the consumer and the provider stub.
+*/
+type proxyAdmin struct {
+	stubsDir     string
+	name         string
+	routes       []utils.ProxyRoute
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+type adminStatusResponse struct {
+	Status string `json:"status"`
+}
+
+type adminWriteResponse struct {
+	Written  bool     `json:"written"`
+	Paths    []string `json:"paths"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type adminErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func newProxyAdmin(stubsDir, name string, routes []utils.ProxyRoute) *proxyAdmin {
+	return &proxyAdmin{
+		stubsDir: stubsDir,
+		name:     name,
+		routes:   routes,
+		shutdown: make(chan struct{}),
+	}
+}
+
+func (a *proxyAdmin) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_signet/health", a.handleHealth)
+	mux.HandleFunc("/_signet/reset", a.handleReset)
+	mux.HandleFunc("/_signet/interactions", a.handleInteractions)
+	mux.HandleFunc("/_signet/write", a.handleWrite)
+	mux.HandleFunc("/_signet/shutdown", a.handleShutdown)
+	return mux
+}
+
+func (a *proxyAdmin) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, adminStatusResponse{Status: "ok"})
+}
+
+func (a *proxyAdmin) handleReset(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	err := utils.ClearMatches(a.stubsDir)
+	if err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, adminErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, adminStatusResponse{Status: "reset"})
+}
+
+func (a *proxyAdmin) handleInteractions(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	interactions, err := utils.GetInteractions(a.stubsDir)
+	if err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, adminErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, interactions)
+}
+
+func (a *proxyAdmin) handleWrite(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	writtenPaths, warnings, err := writeProxyContracts(a.stubsDir, a.name, a.routes)
+	if err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, adminErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, adminWriteResponse{Written: len(writtenPaths) != 0, Paths: writtenPaths, Warnings: warnings})
+}
+
+func (a *proxyAdmin) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	writtenPaths, warnings, err := writeProxyContracts(a.stubsDir, a.name, a.routes)
+	if err != nil {
+		writeAdminJSON(w, http.StatusInternalServerError, adminErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, adminWriteResponse{Written: len(writtenPaths) != 0, Paths: writtenPaths, Warnings: warnings})
+
+	a.shutdownOnce.Do(func() { close(a.shutdown) })
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeAdminJSON(w, http.StatusMethodNotAllowed, adminErrorResponse{Error: "method not allowed, use " + method})
+		return false
+	}
+	return true
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
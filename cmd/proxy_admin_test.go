@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+// copies the recorded mountebank matches fixtures into a temp dir so tests can clear them
+func copyStubsFixture(t *testing.T) string {
+	stubsDir := t.TempDir()
+
+	err := filepath.WalkDir("../data_test/stubs", func(fixturePath string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel("../data_test/stubs", fixturePath)
+		if err != nil {
+			return err
+		}
+
+		matchBytes, err := os.ReadFile(fixturePath)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(filepath.Join(stubsDir, relPath)), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(filepath.Join(stubsDir, relPath), matchBytes, rwPermissions)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stubsDir
+}
+
+func singleRoute(path string) []utils.ProxyRoute {
+	return []utils.ProxyRoute{{ProviderName: "user_service", Path: path}}
+}
+
+func callProxyAdmin(admin *proxyAdmin, method, endpoint string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, endpoint, nil)
+	admin.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+/* ------------- tests ------------- */
+
+func TestProxyAdminHealth(t *testing.T) {
+	admin := newProxyAdmin(t.TempDir(), "service_1", singleRoute(""))
+	rec := callProxyAdmin(admin, http.MethodGet, "/_signet/health")
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestProxyAdminRejectsWrongMethod(t *testing.T) {
+	admin := newProxyAdmin(t.TempDir(), "service_1", singleRoute(""))
+	rec := callProxyAdmin(admin, http.MethodGet, "/_signet/reset")
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestProxyAdminInteractions(t *testing.T) {
+	admin := newProxyAdmin(copyStubsFixture(t), "service_1", singleRoute(""))
+	rec := callProxyAdmin(admin, http.MethodGet, "/_signet/interactions")
+
+	var interactions []map[string]interface{}
+	err := json.NewDecoder(rec.Body).Decode(&interactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("returns the recorded interactions", func(t *testing.T) {
+		if len(interactions) != 2 {
+			t.Fatalf("expected 2 interactions, got %d", len(interactions))
+		}
+	})
+
+	t.Run("interaction has a description", func(t *testing.T) {
+		if interactions[0]["description"] != "GET /users/1 200" {
+			t.Error(interactions[0]["description"])
+		}
+	})
+}
+
+func TestProxyAdminReset(t *testing.T) {
+	admin := newProxyAdmin(copyStubsFixture(t), "service_1", singleRoute(""))
+	rec := callProxyAdmin(admin, http.MethodPost, "/_signet/reset")
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+
+	rec = callProxyAdmin(admin, http.MethodGet, "/_signet/interactions")
+
+	var interactions []map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&interactions)
+	if len(interactions) != 0 {
+		t.Errorf("expected recordings to be cleared, got %d interactions", len(interactions))
+	}
+}
+
+func TestProxyAdminWrite(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "contracts", "cons-prov.json")
+	admin := newProxyAdmin(copyStubsFixture(t), "service_1", singleRoute(pactPath))
+	rec := callProxyAdmin(admin, http.MethodPost, "/_signet/write")
+
+	var respBody adminWriteResponse
+	json.NewDecoder(rec.Body).Decode(&respBody)
+
+	t.Run("reports that the contract was written", func(t *testing.T) {
+		if !respBody.Written || len(respBody.Paths) != 1 || respBody.Paths[0] != pactPath {
+			t.Error(respBody)
+		}
+	})
+
+	t.Run("contract exists on disk", func(t *testing.T) {
+		if _, err := os.Stat(pactPath); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestProxyAdminShutdown(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+	admin := newProxyAdmin(copyStubsFixture(t), "service_1", singleRoute(pactPath))
+	callProxyAdmin(admin, http.MethodPost, "/_signet/shutdown")
+	callProxyAdmin(admin, http.MethodPost, "/_signet/shutdown")
+
+	select {
+	case <-admin.shutdown:
+	default:
+		t.Error("expected shutdown channel to be closed")
+	}
+
+	if _, err := os.Stat(pactPath); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+func TestProxyDecodesBodiesByContentType(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+
+	err, ok := utils.CreatePact("../data_test/body-stubs", pactPath, "service_1", "user_service")
+	if err != nil || !ok {
+		t.Fatal(err)
+	}
+
+	interactions := contractInteractions(t, pactPath)
+	if len(interactions) != 3 {
+		t.Fatalf("expected 3 interactions, got %d", len(interactions))
+	}
+
+	t.Run("form bodies are structured maps", func(t *testing.T) {
+		body := interactions[0]["request"].(map[string]interface{})["body"].(map[string]interface{})
+
+		if body["username"] != "mimmy" || len(body["scope"].([]interface{})) != 2 {
+			t.Error(body)
+		}
+	})
+
+	t.Run("JSON bodies are parsed as objects", func(t *testing.T) {
+		body, ok := interactions[0]["response"].(map[string]interface{})["body"].(map[string]interface{})
+
+		if !ok || body["token"] != "abc" {
+			t.Error(interactions[0]["response"])
+		}
+	})
+
+	t.Run("multipart bodies are a list of parts with their own content types", func(t *testing.T) {
+		parts := interactions[1]["request"].(map[string]interface{})["body"].([]interface{})
+		if len(parts) != 2 {
+			t.Fatal(parts)
+		}
+
+		meta := parts[0].(map[string]interface{})
+		if meta["name"] != "meta" || meta["contentType"] != "application/json" || meta["body"].(map[string]interface{})["userId"] != 1.0 {
+			t.Error(meta)
+		}
+
+		avatar := parts[1].(map[string]interface{})
+		if avatar["filename"] != "avatar.txt" || avatar["body"] != "hello" {
+			t.Error(avatar)
+		}
+	})
+
+	t.Run("binary bodies are base64 encoded with a marker", func(t *testing.T) {
+		response := interactions[2]["response"].(map[string]interface{})
+
+		if response[utils.BodyEncodingKey] != utils.Base64Encoding || response["body"] != "iVBORw0KGgoAAAANSUhEUg==" {
+			t.Error(response)
+		}
+	})
+}
+
+func TestDecodeBody(t *testing.T) {
+	t.Run("base64 encoded text is decoded before parsing", func(t *testing.T) {
+		body, encoding := utils.DecodeBody("eyJpZCI6MX0=", "application/json", utils.Base64Encoding)
+
+		if encoding != "" || body.(map[string]interface{})["id"] != 1.0 {
+			t.Error(body, encoding)
+		}
+	})
+
+	t.Run("invalid UTF-8 is base64 encoded", func(t *testing.T) {
+		_, encoding := utils.DecodeBody(string([]byte{0xff, 0xfe, 0x00}), "", "")
+
+		if encoding != utils.Base64Encoding {
+			t.Error(encoding)
+		}
+	})
+
+	t.Run("bodies which are not valid for their content type are kept as strings", func(t *testing.T) {
+		body, encoding := utils.DecodeBody("not json", "application/json", "")
+
+		if body != "not json" || encoding != "" {
+			t.Error(body, encoding)
+		}
+	})
+}
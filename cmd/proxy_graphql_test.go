@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+func mockServerForGetSchemaReq200OK(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		schemaBytes, err := os.ReadFile("../data_test/schema.graphql")
+		if err != nil {
+			t.Error("Failed to load schema for mock response")
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(schemaBytes)
+	}))
+}
+
+/* ------------- tests ------------- */
+
+func TestProxyRecordsGraphQLOperations(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+
+	err, ok := utils.CreatePact("../data_test/graphql-stubs", pactPath, "service_1", "user_service")
+	if err != nil || !ok {
+		t.Fatal(err)
+	}
+
+	interactions := contractInteractions(t, pactPath)
+
+	t.Run("descriptions are derived from operation names", func(t *testing.T) {
+		if interactions[0]["description"] != "POST /graphql query GetUser 200" {
+			t.Error(interactions[0]["description"])
+		}
+
+		if interactions[1]["description"] != "POST /graphql mutation RenameUser 200" {
+			t.Error(interactions[1]["description"])
+		}
+	})
+
+	t.Run("queries are normalized", func(t *testing.T) {
+		body := interactions[0]["request"].(map[string]interface{})["body"].(map[string]interface{})
+		expected := "query GetUser($id:ID!){user(id:$id){userId username}}"
+
+		if body["query"] != expected {
+			t.Error(body["query"])
+		}
+	})
+
+	t.Run("variables and operation name are kept", func(t *testing.T) {
+		body := interactions[0]["request"].(map[string]interface{})["body"].(map[string]interface{})
+
+		if body["operationName"] != "GetUser" || body["variables"].(map[string]interface{})["id"] != "1" {
+			t.Error(body)
+		}
+	})
+
+	t.Run("string arguments are not altered", func(t *testing.T) {
+		body := interactions[1]["request"].(map[string]interface{})["body"].(map[string]interface{})
+
+		if !strings.Contains(body["query"].(string), `renameUser(id:"1" username:"mim")`) {
+			t.Error(body["query"])
+		}
+	})
+}
+
+func TestProxyValidatesGraphQLOperations(t *testing.T) {
+	server := mockServerForGetSchemaReq200OK(t)
+	defer server.Close()
+
+	brokerURL = server.URL
+	validateGraphQL = true
+	defer teardown()
+
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+	route := utils.ProxyRoute{ProviderName: "user_service", Path: pactPath}
+
+	writtenPaths, warnings, err := writeProxyContracts("../data_test/graphql-stubs", "service_1", []utils.ProxyRoute{route})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("contract is still written", func(t *testing.T) {
+		if len(writtenPaths) != 1 {
+			t.Error(writtenPaths)
+		}
+	})
+
+	t.Run("warns about fields missing from the schema", func(t *testing.T) {
+		if len(warnings) != 1 {
+			t.Fatalf("expected 1 warning, got %v", warnings)
+		}
+
+		expected := `user_service: mutation RenameUser: field "deleteEverything" does not exist on type Mutation`
+		if warnings[0] != expected {
+			t.Error(warnings[0])
+		}
+	})
+}
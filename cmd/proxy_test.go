@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+func callProxy(argsAndFlags []string) actualOut {
+	actual := new(bytes.Buffer)
+	RootCmd.SetOut(actual)
+	RootCmd.SetErr(actual)
+	RootCmd.SetArgs(append([]string{"proxy"}, argsAndFlags...))
+	RootCmd.Execute()
+	return actualOut{actual.String()}
+}
+
+func multiProviderRoutes(contractsDir string) []utils.ProxyRoute {
+	return []utils.ProxyRoute{
+		{
+			PathPrefix:   "/users",
+			Target:       "http://localhost:3002",
+			ProviderName: "user_service",
+			Path:         filepath.Join(contractsDir, "service_1-user_service.json"),
+		},
+		{
+			Host:         "orders.local",
+			Target:       "http://localhost:3003",
+			ProviderName: "order_service",
+			Path:         filepath.Join(contractsDir, "service_1-order_service.json"),
+		},
+	}
+}
+
+/* ------------- tests ------------- */
+
+func TestProxyNoPath(t *testing.T) {
+	flags := []string{
+		"--port", "3004",
+		"--target", "http://localhost:3002",
+		"--name", "service_1",
+		"--provider-name", "user_service",
+	}
+	actual := callProxy(flags)
+	expected := "Error: No --path was provided."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestProxyRoutesRequireTarget(t *testing.T) {
+	routes := []utils.ProxyRoute{{PathPrefix: "/users", ProviderName: "user_service", Path: "./contracts/users.json"}}
+	err := validateProxyFlags("", "3004", "", "service_1", "", routes)
+
+	if err == nil || err.Error() != "proxy route 1 has no target" {
+		t.Error(err)
+	}
+}
+
+func TestProxyRoutesExcludeTargetFlag(t *testing.T) {
+	routes := multiProviderRoutes("./contracts")
+	err := validateProxyFlags("", "3004", "http://localhost:3002", "service_1", "", routes)
+
+	if err == nil {
+		t.Error("expected --target to be rejected when proxy.routes is set")
+	}
+}
+
+func TestProxyRoutesExcludePathFlag(t *testing.T) {
+	routes := multiProviderRoutes("./contracts")
+	err := validateProxyFlags("./contracts/users.json", "3004", "", "service_1", "", routes)
+
+	expected := "--path cannot be used together with proxy.routes, set a path for each route instead"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestProxyRoutesExcludeProviderNameFlag(t *testing.T) {
+	routes := multiProviderRoutes("./contracts")
+	err := validateProxyFlags("", "3004", "", "service_1", "user_service", routes)
+
+	expected := "--provider-name cannot be used together with proxy.routes, set a provider-name for each route instead"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestSetupMbConfigWithRoutes(t *testing.T) {
+	realosWriteFile := osWriteFile
+	defer func() { osWriteFile = realosWriteFile }()
+
+	var configBytes []byte
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		configBytes = data
+		return nil
+	}
+
+	err := setupMbConfig("3004", "config.ejs", multiProviderRoutes("./contracts"), proxyTLSMaterial{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var proxyConfig utils.ProxyConfig
+	json.Unmarshal(configBytes, &proxyConfig)
+
+	t.Run("has one stub per route", func(t *testing.T) {
+		if len(proxyConfig.Stubs) != 2 {
+			t.Fatalf("expected 2 stubs, got %d", len(proxyConfig.Stubs))
+		}
+	})
+
+	t.Run("stubs proxy to the route targets", func(t *testing.T) {
+		if proxyConfig.Stubs[0].Responses[0].Proxy.To != "http://localhost:3002" ||
+			proxyConfig.Stubs[1].Responses[0].Proxy.To != "http://localhost:3003" {
+			t.Error(proxyConfig.Stubs)
+		}
+	})
+
+	t.Run("stubs have predicates", func(t *testing.T) {
+		if len(proxyConfig.Stubs[0].Predicates) != 1 || len(proxyConfig.Stubs[1].Predicates) != 1 {
+			t.Error(proxyConfig.Stubs)
+		}
+	})
+}
+
+func TestCreatePactsPerRoute(t *testing.T) {
+	contractsDir := t.TempDir()
+	routes := multiProviderRoutes(contractsDir)
+
+	writtenPaths, _, err := utils.CreatePacts(copyStubsFixture(t), "service_1", routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("writes one contract per provider", func(t *testing.T) {
+		if len(writtenPaths) != 2 {
+			t.Fatalf("expected 2 contracts, got %d", len(writtenPaths))
+		}
+	})
+
+	t.Run("each contract only has its provider's interactions", func(t *testing.T) {
+		for i, route := range routes {
+			pact, err := utils.LoadContract(route.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			interactions := pact.Interactions.([]interface{})
+			if len(interactions) != 1 {
+				t.Errorf("route %d: expected 1 interaction, got %d", i, len(interactions))
+			}
+
+			if pact.Provider.(map[string]interface{})["name"] != route.ProviderName {
+				t.Error(pact.Provider)
+			}
+		}
+	})
+}
+
+func TestCreatePactsSkipsInvalidMatches(t *testing.T) {
+	pactPath := filepath.Join(t.TempDir(), "cons-prov.json")
+	route := utils.ProxyRoute{ProviderName: "user_service", Path: pactPath}
+
+	writtenPaths, skipped, err := utils.CreatePacts("../data_test/invalid-stubs", "service_1", []utils.ProxyRoute{route})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("still writes the valid interactions", func(t *testing.T) {
+		if len(writtenPaths) != 1 {
+			t.Fatal(writtenPaths)
+		}
+
+		pact, err := utils.LoadContract(pactPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pact.Interactions.([]interface{})) != 2 {
+			t.Error(pact.Interactions)
+		}
+	})
+
+	t.Run("reports each invalid match file by name", func(t *testing.T) {
+		if len(skipped) != 3 {
+			t.Fatalf("expected 3 skipped match files, got %v", skipped)
+		}
+
+		for _, skippedErr := range skipped {
+			var matchErr *utils.MatchError
+			if !errors.As(skippedErr, &matchErr) || !strings.Contains(skippedErr.Error(), matchErr.Path) {
+				t.Error(skippedErr)
+			}
+		}
+	})
+}
+
+func TestCreatePactsBeforeAnythingWasRecorded(t *testing.T) {
+	stubsDir := filepath.Join(t.TempDir(), "3004", "stubs")
+	writtenPaths, _, err := utils.CreatePacts(stubsDir, "service_1", singleRoute("./cons-prov.json"))
+
+	if err != nil || len(writtenPaths) != 0 {
+		t.Error(writtenPaths, err)
+	}
+}
+
+func TestGetMatchPathsReportsWalkErrors(t *testing.T) {
+	_, err := utils.GetMatchPaths("../data_test/non-existant")
+
+	if err == nil {
+		t.Error("expected an error for a directory that does not exist")
+	}
+}
+
+func TestExitedOnInterrupt(t *testing.T) {
+	realInterruptGracePeriod := interruptGracePeriod
+	defer func() { interruptGracePeriod = realInterruptGracePeriod }()
+	interruptGracePeriod = 500 * time.Millisecond
+
+	t.Run("waits for Ctrl + C which arrives after mountebank exited", func(t *testing.T) {
+		interrupt := make(chan os.Signal, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			interrupt <- os.Interrupt
+		}()
+
+		if !exitedOnInterrupt(interrupt, nil) {
+			t.Error("expected the exit to be caused by Ctrl + C")
+		}
+	})
+
+	t.Run("treats an exit without Ctrl + C as unexpected", func(t *testing.T) {
+		interruptGracePeriod = 10 * time.Millisecond
+		if exitedOnInterrupt(make(chan os.Signal, 1), errors.New("exit status 1")) {
+			t.Error("expected the exit to be unexpected")
+		}
+	})
+
+	t.Run("mountebank terminated by SIGINT", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("signals are not supported on windows")
+		}
+
+		interruptGracePeriod = 10 * time.Millisecond
+		err := exec.Command("sh", "-c", "kill -INT $$").Run()
+		if !exitedOnInterrupt(make(chan os.Signal, 1), err) {
+			t.Error(err)
+		}
+	})
+}
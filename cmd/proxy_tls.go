@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+// TLS settings for the proxy listener and for the connection to the target
+type proxyTLSOptions struct {
+	certPath           string
+	keyPath            string
+	targetCAPath       string
+	targetCertPath     string
+	targetKeyPath      string
+	insecureSkipVerify bool
+}
+
+// PEM contents handed to mountebank for the HTTPS listener
+type proxyTLSMaterial struct {
+	cert string
+	key  string
+}
+
+func validateProxyTLSFlags(opts proxyTLSOptions) error {
+	if (len(opts.certPath) == 0) != (len(opts.keyPath) == 0) {
+		return errors.New("--tls-cert and --tls-key must be provided together")
+	}
+
+	if (len(opts.targetCertPath) == 0) != (len(opts.targetKeyPath) == 0) {
+		return errors.New("--target-cert and --target-key must be provided together")
+	}
+
+	if len(opts.targetCAPath) != 0 && opts.insecureSkipVerify {
+		return errors.New("--target-ca cannot be used together with --insecure-skip-verify")
+	}
+
+	return nil
+}
+
+func loadProxyTLSMaterial(opts proxyTLSOptions) (proxyTLSMaterial, error) {
+	var material proxyTLSMaterial
+	var err error
+
+	if len(opts.certPath) != 0 {
+		material.cert, err = readPEMFile(opts.certPath, "--tls-cert")
+		if err != nil {
+			return proxyTLSMaterial{}, err
+		}
+
+		material.key, err = readPEMFile(opts.keyPath, "--tls-key")
+		if err != nil {
+			return proxyTLSMaterial{}, err
+		}
+	}
+
+	return material, nil
+}
+
+func readPEMFile(path, flag string) (string, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.New("failed to read " + flag + " file: " + err.Error())
+	}
+	return string(pemBytes), nil
+}
+
+/*
+mountebank proxies to HTTPS targets without verifying their certificates, and
+cannot be given a CA to trust. Each HTTPS target is reached through a local
+forwarder instead, which connects to the target using --target-ca,
+--insecure-skip-verify, and --target-cert, while mountebank proxies to the
+forwarder over plain HTTP on the loopback interface. Routes are returned with
+their targets pointing at the forwarders, along with a function that stops them.
+*/
+func startTargetForwarders(routes []utils.ProxyRoute, opts proxyTLSOptions, errOut io.Writer) ([]utils.ProxyRoute, func(), error) {
+	servers := []*http.Server{}
+	stop := func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+
+	mbRoutes := []utils.ProxyRoute{}
+	for _, route := range routes {
+		targetURL, err := url.Parse(route.Target)
+		if err != nil {
+			stop()
+			return nil, nil, errors.New("--target is not a valid URL: " + err.Error())
+		}
+
+		if targetURL.Scheme != "https" {
+			mbRoutes = append(mbRoutes, route)
+			continue
+		}
+
+		tlsConfig, err := targetTLSConfig(targetURL, opts)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			stop()
+			return nil, nil, errors.New("failed to start the forwarder for " + route.Target + ": " + err.Error())
+		}
+
+		server := &http.Server{Handler: newTargetForwarder(targetURL, tlsConfig, errOut)}
+		servers = append(servers, server)
+		go server.Serve(listener)
+
+		route.Target = "http://" + listener.Addr().String()
+		mbRoutes = append(mbRoutes, route)
+	}
+
+	return mbRoutes, stop, nil
+}
+
+func targetTLSConfig(targetURL *url.URL, opts proxyTLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         targetURL.Hostname(),
+		InsecureSkipVerify: opts.insecureSkipVerify,
+	}
+
+	if len(opts.targetCAPath) != 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		caBytes, err := os.ReadFile(opts.targetCAPath)
+		if err != nil {
+			return nil, errors.New("failed to read --target-ca file: " + err.Error())
+		}
+
+		if ok := rootCAs.AppendCertsFromPEM(caBytes); !ok {
+			return nil, errors.New("--target-ca does not contain any PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if len(opts.targetCertPath) != 0 {
+		clientCert, err := tls.LoadX509KeyPair(opts.targetCertPath, opts.targetKeyPath)
+		if err != nil {
+			return nil, errors.New("failed to load --target-cert and --target-key: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	return tlsConfig, nil
+}
+
+// the forwarder sends requests on unchanged, except for the scheme, host, and base path of the target
+func newTargetForwarder(targetURL *url.URL, tlsConfig *tls.Config, errOut io.Writer) http.Handler {
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			req.Host = targetURL.Host
+			if len(targetURL.Path) != 0 {
+				if len(req.URL.RawPath) != 0 {
+					req.URL.RawPath = singleJoiningSlash(targetURL.EscapedPath(), req.URL.RawPath)
+				}
+				req.URL.Path = singleJoiningSlash(targetURL.Path, req.URL.Path)
+			}
+			if len(targetURL.RawQuery) != 0 {
+				req.URL.RawQuery = joinQuery(targetURL.RawQuery, req.URL.RawQuery)
+			}
+			// a nil value stops the reverse proxy from adding the header
+			req.Header["X-Forwarded-For"] = nil
+		},
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			message := "signet proxy could not reach " + targetURL.Scheme + "://" + targetURL.Host + ": " + err.Error()
+
+			var verifyErr *tls.CertificateVerificationError
+			if errors.As(err, &verifyErr) {
+				message += " (use --target-ca to trust a self-signed certificate, or --insecure-skip-verify to skip verification)"
+			}
+
+			fmt.Fprintln(errOut, colorRed+"Warning"+colorReset+" - "+message)
+			http.Error(w, message, http.StatusBadGateway)
+		},
+	}
+}
+
+// joins the target's base path and the request path the same way as httputil.NewSingleHostReverseProxy
+func singleJoiningSlash(a, b string) string {
+	aSlash := strings.HasSuffix(a, "/")
+	bSlash := strings.HasPrefix(b, "/")
+	switch {
+	case aSlash && bSlash:
+		return a + b[1:]
+	case !aSlash && !bSlash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+func joinQuery(targetQuery, requestQuery string) string {
+	if len(requestQuery) == 0 {
+		return targetQuery
+	}
+	return targetQuery + "&" + requestQuery
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/fs"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	err := os.WriteFile(caPath, caPEM, rwPermissions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return caPath
+}
+
+// writeClientCert writes a self-signed client certificate and key, and returns a pool that trusts it
+func writeClientCert(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signet-proxy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.pem")
+	keyPath := filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), rwPermissions)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), rwPermissions)
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certPath, keyPath, pool
+}
+
+// getThroughForwarder starts forwarders for the target, and requests path through the forwarder
+func getThroughForwarder(t *testing.T, target, path string, opts proxyTLSOptions) (int, string, string) {
+	var errOut strings.Builder
+	mbRoutes, stop, err := startTargetForwarders([]utils.ProxyRoute{{Target: target}}, opts, &errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	res, err := http.Get(mbRoutes[0].Target + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), errOut.String()
+}
+
+/* ------------- tests ------------- */
+
+func TestProxyTLSFlagsRequireCertAndKey(t *testing.T) {
+	err := validateProxyTLSFlags(proxyTLSOptions{certPath: "cert.pem"})
+	if err == nil || err.Error() != "--tls-cert and --tls-key must be provided together" {
+		t.Error(err)
+	}
+
+	err = validateProxyTLSFlags(proxyTLSOptions{targetKeyPath: "key.pem"})
+	if err == nil || err.Error() != "--target-cert and --target-key must be provided together" {
+		t.Error(err)
+	}
+}
+
+func TestSetupMbConfigWithTLS(t *testing.T) {
+	realosWriteFile := osWriteFile
+	defer func() { osWriteFile = realosWriteFile }()
+
+	var configBytes []byte
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		configBytes = data
+		return nil
+	}
+
+	tlsMaterial := proxyTLSMaterial{cert: "CERT", key: "KEY"}
+	err := setupMbConfig("3004", "config.ejs", []utils.ProxyRoute{{Target: "https://localhost:3002"}}, tlsMaterial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var proxyConfig utils.ProxyConfig
+	json.Unmarshal(configBytes, &proxyConfig)
+
+	t.Run("imposter listens on https with the provided certificate", func(t *testing.T) {
+		if proxyConfig.Protocol != "https" || proxyConfig.Cert != "CERT" || proxyConfig.Key != "KEY" {
+			t.Error(proxyConfig)
+		}
+	})
+}
+
+func TestSetupMbConfigWithoutTLS(t *testing.T) {
+	realosWriteFile := osWriteFile
+	defer func() { osWriteFile = realosWriteFile }()
+
+	var configBytes []byte
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		configBytes = data
+		return nil
+	}
+
+	err := setupMbConfig("3004", "config.ejs", []utils.ProxyRoute{{Target: "http://localhost:3002"}}, proxyTLSMaterial{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(configBytes), "cert") || !strings.Contains(string(configBytes), `"protocol":"http"`) {
+		t.Error(string(configBytes))
+	}
+}
+
+func TestStartTargetForwarders(t *testing.T) {
+	routes := []utils.ProxyRoute{
+		{Target: "http://localhost:3002"},
+		{Target: "https://localhost:3003", PathPrefix: "/orders"},
+	}
+
+	mbRoutes, stop, err := startTargetForwarders(routes, proxyTLSOptions{}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	t.Run("http targets are proxied to directly", func(t *testing.T) {
+		if mbRoutes[0].Target != "http://localhost:3002" {
+			t.Error(mbRoutes[0])
+		}
+	})
+
+	t.Run("https targets are proxied to through a local forwarder", func(t *testing.T) {
+		if !strings.HasPrefix(mbRoutes[1].Target, "http://127.0.0.1:") || mbRoutes[1].PathPrefix != "/orders" {
+			t.Error(mbRoutes[1])
+		}
+	})
+
+	t.Run("the original routes are unchanged", func(t *testing.T) {
+		if routes[1].Target != "https://localhost:3003" {
+			t.Error(routes[1])
+		}
+	})
+}
+
+func TestTargetForwarderTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	t.Run("rejects a self-signed target on every request", func(t *testing.T) {
+		status, _, errOut := getThroughForwarder(t, server.URL, "/users", proxyTLSOptions{})
+		if status != http.StatusBadGateway || !strings.Contains(errOut, "use --target-ca to trust a self-signed certificate") {
+			t.Error(status, errOut)
+		}
+	})
+
+	t.Run("trusts a target signed by --target-ca", func(t *testing.T) {
+		status, body, _ := getThroughForwarder(t, server.URL, "/users", proxyTLSOptions{targetCAPath: writeServerCA(t, server)})
+		if status != http.StatusOK || body != "/users" {
+			t.Error(status, body)
+		}
+	})
+
+	t.Run("skips verification with --insecure-skip-verify", func(t *testing.T) {
+		status, body, _ := getThroughForwarder(t, server.URL, "/users", proxyTLSOptions{insecureSkipVerify: true})
+		if status != http.StatusOK || body != "/users" {
+			t.Error(status, body)
+		}
+	})
+}
+
+func TestTargetForwarderBasePath(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	opts := proxyTLSOptions{insecureSkipVerify: true}
+
+	t.Run("joins the request path to the target's path", func(t *testing.T) {
+		_, body, _ := getThroughForwarder(t, server.URL+"/base", "/users/1?active=true", opts)
+		if body != "/base/users/1?active=true" {
+			t.Error(body)
+		}
+	})
+
+	t.Run("does not double the slash of a target path ending in one", func(t *testing.T) {
+		_, body, _ := getThroughForwarder(t, server.URL+"/base/", "/users", opts)
+		if body != "/base/users" {
+			t.Error(body)
+		}
+	})
+}
+
+func TestTargetForwarderClientCert(t *testing.T) {
+	certPath, keyPath, clientCAs := writeClientCert(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caPath := writeServerCA(t, server)
+
+	t.Run("presents --target-cert to the target", func(t *testing.T) {
+		opts := proxyTLSOptions{targetCAPath: caPath, targetCertPath: certPath, targetKeyPath: keyPath}
+		status, body, _ := getThroughForwarder(t, server.URL, "/", opts)
+		if status != http.StatusOK || body != "signet-proxy" {
+			t.Error(status, body)
+		}
+	})
+
+	t.Run("fails without a client certificate", func(t *testing.T) {
+		status, _, _ := getThroughForwarder(t, server.URL, "/", proxyTLSOptions{targetCAPath: caPath})
+		if status != http.StatusBadGateway {
+			t.Error(status)
+		}
+	})
+}
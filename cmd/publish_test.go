@@ -289,3 +289,30 @@ func TestPublishProviderYAMLSpec(t *testing.T) {
 
 	teardown()
 }
+
+func TestPublishProviderGraphQLSchema(t *testing.T) {
+	server, reqBody := mockServerForJSONReq201Created[utils.ProviderBody](t)
+	defer server.Close()
+
+	flags := []string{
+		"--path=../data_test/schema.graphql",
+		"--broker-url", server.URL,
+		"--type", "provider",
+		"--name", "user_service",
+	}
+	callPublish(flags)
+
+	t.Run("has correct specFormat", func(t *testing.T) {
+		if reqBody.SpecFormat != "graphql" {
+			t.Error()
+		}
+	})
+
+	t.Run("spec is the schema as a string", func(t *testing.T) {
+		if _, ok := reqBody.Spec.(string); !ok {
+			t.Error()
+		}
+	})
+
+	teardown()
+}
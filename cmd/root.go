@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -12,7 +13,7 @@ const colorGreen = "\033[32m"
 const colorRed = "\033[31m"
 const colorBlue = "\033[34m"
 const colorReset = "\033[0m"
-const stackName = "signetbroker"
+const defaultStackName = "signetbroker"
 
 var IgnoreConfig bool
 var brokerURL string
@@ -22,10 +23,19 @@ var version string
 var branch string
 var environment string
 
+// an invalid config file is reported when a command runs, so that signet doctor can still check it
+var configErr error
+
 var RootCmd = &cobra.Command{
 	Use:   "signet",
 	Short: "The command line interface for the Signet contract testing framework",
 	Long:  `The command line interface for the Signet contract testing framework`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configErr != nil && cmd.Name() != "doctor" {
+			return errors.New("unable to read .signetrc.yaml - run signet doctor for details: " + configErr.Error())
+		}
+		return nil
+	},
 }
 
 func Execute() {
@@ -53,7 +63,7 @@ func readConfigFile() {
 		viper.SetConfigType("yaml")
 		if err := viper.ReadInConfig(); err != nil {
 			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				panic(err)
+				configErr = err
 			}
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	client "github.com/signet-framework/signet-cli/client"
@@ -24,6 +25,54 @@ func teardown() {
 	environment = ""
 	delete = false
 	providerURL = ""
+	port = ""
+	target = ""
+	providerName = ""
+	routes = nil
+	validateGraphQL = false
+	harPath = ""
+	matchesPath = ""
+	baseURL = ""
+	consumerName = ""
+	adminPort = ""
+	tlsOpts = proxyTLSOptions{}
+	// map flags add to their existing map once they were set, so they are reset to empty maps rather than nil
+	dreddHooks = utils.DreddHooks{RequestHeaders: map[string]string{}}
+	localSpecPath = ""
+	noPublish = false
+	reports = map[string]string{}
+	concurrency = 1
+	mode = specMode
+	contractPaths = nil
+	showCoverage = false
+	minCoverage = 0
+	fuzz = false
+	workDir = ""
+	keepArtifacts = false
+	stackName = defaultStackName
+	undeployStackName = ""
+	templatePath = ""
+	parametersPath = ""
+	vpcID = ""
+	loadBalancerSubnets = nil
+	serviceSubnets = nil
+	taskCPU = ""
+	taskMemory = ""
+	desiredCount = ""
+	dbInstanceClass = ""
+	dbStorage = ""
+	dbBackupRetention = ""
+	logRetention = ""
+	brokerImage = ""
+	upgrade = false
+	assumeYes = false
+	dryRun = false
+	undeployDryRun = false
+	undeployConfirm = ""
+	deployTimeout = defaultStackTimeout
+	undeployTimeout = defaultStackTimeout
+	detectDrift = false
+	writeConfig = false
 }
 
 type actualOut struct {
@@ -43,6 +92,28 @@ func (ao actualOut) startsWith(expected string, t *testing.T) {
 	}
 }
 
+func (ao actualOut) contains(expected string, t *testing.T) {
+	if !strings.Contains(ao.actual, expected) {
+		fmt.Println("ACTUAL: ")
+		fmt.Println(ao.actual)
+		t.Error("expected output to contain " + expected)
+	}
+}
+
+// loads the interactions of a consumer contract written during a test
+func contractInteractions(t *testing.T, pactPath string) []map[string]interface{} {
+	contract, err := utils.LoadContract(pactPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interactions := []map[string]interface{}{}
+	for _, interaction := range contract.Interactions.([]interface{}) {
+		interactions = append(interactions, interaction.(map[string]interface{}))
+	}
+	return interactions
+}
+
 type requestBody interface {
 	utils.ConsumerBody | utils.ProviderBody | utils.EnvBody | utils.DeploymentBody
 }
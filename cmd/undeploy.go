@@ -4,19 +4,56 @@ import (
 	"errors"
 	"fmt"
 	"context"
-	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 )
 
+// separate from stackName, because its default falls back to deploy.stack-name
+var undeployStackName string
+
 var undeployCmd = &cobra.Command{
 	Use:   "undeploy",
 	Short: "Tear down the Signet broker deployment on AWS ECS",
-	Long:  `Tear down the Signet broker deployment on AWS ECS`,
+	Long:  `Tear down the Signet broker deployment on AWS ECS
+
+	flags:
+
+	--stack-name        the name of the CloudFormation stack to delete (optional, defaults to deploy.stack-name in .signetrc.yaml, or signetbroker)
+
+	--confirm           the name of the stack being deleted, to confirm that it should be deleted along with the broker's database (required unless --dry-run is passed)
+
+	--dry-run           list the resources that would be deleted, and which of them hold data, without deleting anything (optional)
+
+	--timeout           how long to wait for the stack to be deleted, ex. 45m (optional, defaults to 30m)
+
+	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
+`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		stackName = resolveStackName("undeploy.stack-name")
+		err := validateStackName(stackName)
+		if err != nil {
+			return err
+		}
+
+		undeployTimeout = viper.GetDuration("undeploy.timeout")
+		err = validateTimeout(undeployTimeout)
+		if err != nil {
+			return err
+		}
+
+		undeployDryRun = viper.GetBool("undeploy.dry-run")
+		if !undeployDryRun {
+			err = validateUndeployConfirm(undeployConfirm)
+			if err != nil {
+				return err
+			}
+		}
+
 		dsInput := &cloudformation.DeleteStackInput{StackName: aws.String(stackName)}
 
 		cfg, err := config.LoadDefaultConfig(context.TODO())
@@ -25,14 +62,28 @@ var undeployCmd = &cobra.Command{
     }
 		
 		cfClient := cloudformation.NewFromConfig(cfg)
+		if undeployDryRun {
+			return previewUndeploy(cfClient)
+		}
+
+		stack, err := describeStack(cfClient, stackName)
+		if err != nil {
+			return err
+		}
+
+		tracker, err := startStackEventTracker(cfClient, aws.ToString(stack.StackId))
+		if err != nil {
+			return err
+		}
+
 		_, err = cfClient.DeleteStack(context.TODO(), dsInput)
 		if err != nil {
 			return errors.New("unable to delete CloudFormation stack: " + err.Error())
 		}
 
-		fmt.Println(colorGreen + "Undeploying" + colorReset + " - tearing down the Signet broker ECS Cluster, this will take a few minutes...")
+		fmt.Println(colorGreen + "Undeploying" + colorReset + " - tearing down the Signet broker ECS Cluster in stack " + stackName + ", this will take a few minutes...")
 
-		if err := waitForUndeploymentDone(cfClient); err != nil {
+		if err := waitForUndeploymentDone(cfClient, tracker); err != nil {
 			return err
 		}
 
@@ -42,22 +93,38 @@ var undeployCmd = &cobra.Command{
 	},
 }
 
-func waitForUndeploymentDone(cfClient *cloudformation.Client) error {
-	waiter := cloudformation.NewStackDeleteCompleteWaiter(cfClient)
-	waitDuration, err := time.ParseDuration("15m")
+func waitForUndeploymentDone(cfClient *cloudformation.Client, tracker *stackEventTracker) error {
+	stack, err := watchStack(cfClient, tracker, undeployTimeout)
 	if err != nil {
 		return err
 	}
 
-	name := stackName
-	dsInput := &cloudformation.DescribeStacksInput{StackName: &name}
-	if err := waiter.Wait(context.TODO(), dsInput, waitDuration); err != nil { // changed this from WaitForOutput before committing - untested
-		return errors.New("error while waiting for CloudFormation stack to be deleted - it is likely that Signet CLI simply timed out. Check your AWS console for the status of the teardown operations: " + err.Error())
+	if stack.StackStatus != types.StackStatusDeleteComplete {
+		return errors.New(describeStackFailure("teardown", stack, tracker) + " - fix or remove the resource, then run undeploy again")
 	}
 
 	return nil
 }
-	
+
+/*
+resolveStackName reads the stack name of a command that operates on an
+existing stack. Without its own flag or config, it uses deploy.stack-name, so
+that a config file for a staging broker only needs to set it once.
+*/
+func resolveStackName(key string) string {
+	if name := viper.GetString(key); len(name) != 0 {
+		return name
+	}
+	if name := viper.GetString("deploy.stack-name"); len(name) != 0 {
+		return name
+	}
+	return defaultStackName
+}
+
 func init() {
 	RootCmd.AddCommand(undeployCmd)
+
+	undeployCmd.Flags().StringVar(&undeployStackName, "stack-name", "", "the name of the CloudFormation stack to delete (optional)")
+
+	viper.BindPFlag("undeploy.stack-name", undeployCmd.Flags().Lookup("stack-name"))
 }
\ No newline at end of file
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	client "github.com/signet-framework/signet-cli/client"
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+const specMode = "spec"
+const contractsMode = "contracts"
+
+/*
+verifyConsumerContracts replays the interactions in the consumer contracts for
+the provider against --provider-url, and reports the results per consumer. The
+contracts are fetched from the broker, unless local files are given with
+--contracts. Contract verification results are not published to the broker.
+*/
+func verifyConsumerContracts(cmd *cobra.Command) error {
+	var contracts []utils.ConsumerContract
+	var err error
+	if len(contractPaths) != 0 {
+		contracts, err = loadLocalContracts(contractPaths)
+	} else {
+		contracts, err = fetchBrokerContracts()
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(contracts) == 0 {
+		fmt.Println("No consumer contracts were found for " + name + describeEnvironment(environment))
+		return nil
+	}
+
+	verifier := utils.ContractVerifier{ProviderURL: providerURL, Hooks: dreddHooks}
+	results := []utils.VerificationResult{}
+	failedConsumers := 0
+
+	for _, contract := range contracts {
+		contractResults := verifier.VerifyContract(contract)
+		results = append(results, contractResults...)
+
+		if printContractResults(contract, contractResults) {
+			failedConsumers++
+		}
+	}
+
+	if len(reports) != 0 {
+		err = writeVerificationReports(results, reports, name, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if failedConsumers != 0 {
+		cmd.SilenceUsage = true
+		return errors.New("consumer contract verification failed for " + strconv.Itoa(failedConsumers) + " of " + strconv.Itoa(len(contracts)) + " consumers")
+	}
+
+	return nil
+}
+
+func fetchBrokerContracts() ([]utils.ConsumerContract, error) {
+	contractsBytes, err := client.GetContracts(brokerURL, name, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ParseBrokerContracts(contractsBytes)
+}
+
+// local contract files override the broker, and must be for the --name provider
+func loadLocalContracts(paths []string) ([]utils.ConsumerContract, error) {
+	contracts := []utils.ConsumerContract{}
+	for _, path := range paths {
+		contract, err := utils.LoadConsumerContract(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if contract.ProviderName != name {
+			return nil, errors.New("contract " + path + " is for provider \"" + contract.ProviderName + "\", not " + name)
+		}
+
+		contracts = append(contracts, contract)
+	}
+
+	return contracts, nil
+}
+
+// prints the results for one consumer, and returns whether any interaction failed
+func printContractResults(contract utils.ConsumerContract, results []utils.VerificationResult) bool {
+	failed := 0
+	for _, result := range results {
+		if result.Status == "fail" {
+			failed++
+		}
+	}
+
+	consumer := contract.ConsumerName
+	if len(contract.ConsumerVersion) != 0 {
+		consumer += " (version " + contract.ConsumerVersion + ")"
+	}
+
+	if failed == 0 {
+		fmt.Println(colorGreen + "PASS" + colorReset + ": " + name + " satisfies the contract with " + consumer + " - " + strconv.Itoa(len(results)) + " interactions verified")
+	} else {
+		fmt.Println(colorRed + "FAIL" + colorReset + ": " + name + " does not satisfy the contract with " + consumer + " - " + strconv.Itoa(failed) + " of " + strconv.Itoa(len(results)) + " interactions failed")
+	}
+
+	for _, result := range results {
+		if result.Status != "fail" {
+			continue
+		}
+
+		fmt.Println("  " + result.Name + " (" + result.Operation + ")")
+		for _, failure := range result.Failures {
+			fmt.Println("    - " + failure)
+		}
+	}
+	fmt.Println()
+
+	return failed != 0
+}
+
+func describeEnvironment(environment string) string {
+	if len(environment) == 0 {
+		return ""
+	}
+	return " in " + environment + " environment"
+}
+
+/*
+validateContractsFlags checks the flags of --mode contracts. The broker is only
+needed to fetch the contracts, so --broker-url is not required with --contracts.
+*/
+func validateContractsFlags(brokerURL, name, providerURL string) error {
+	if len(contractPaths) == 0 && len(brokerURL) == 0 {
+		return errors.New("No --broker-url was provided. This flag is required for --mode contracts, unless --contracts is set.")
+	}
+
+	if len(name) == 0 {
+		return errors.New("No --name was provided. This is a required flag.")
+	}
+
+	if len(providerURL) == 0 {
+		return errors.New("No --provider-url was provided. This is a required flag.")
+	}
+
+	if len(contractPaths) != 0 && len(environment) != 0 {
+		return errors.New("--environment cannot be used together with --contracts, it only filters the contracts fetched from the broker")
+	}
+
+	if len(localSpecPath) != 0 {
+		return errors.New("--spec cannot be used with --mode contracts")
+	}
+
+	if fuzz {
+		return errors.New("--fuzz cannot be used with --mode contracts")
+	}
+
+	if concurrency != 1 {
+		return errors.New("--concurrency cannot be used with --mode contracts")
+	}
+
+	return nil
+}
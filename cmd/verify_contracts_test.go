@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+func mockServerForGetContractsReq200OK(t *testing.T) (*httptest.Server, *http.Request) {
+	req := &http.Request{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*req = *r
+
+		contractsBytes, err := os.ReadFile("../data_test/broker-contracts.json")
+		if err != nil {
+			t.Error("Failed to load contracts for mock response")
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(contractsBytes)
+	}))
+
+	return server, req
+}
+
+// a provider which satisfies service_1's contract, but not service_2's
+func mockProviderForContracts(t *testing.T, states *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /_state":
+			var state map[string]string
+			json.NewDecoder(r.Body).Decode(&state)
+			*states = append(*states, state["action"]+": "+state["state"])
+		case "GET /users/1":
+			w.Write([]byte(`{"userId": 1, "username": "bob", "email": "bob@signet.test"}`))
+		case "POST /users":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"username":"mim"}` {
+				t.Error(string(body))
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"userId": 2}`))
+		case "GET /users":
+			if r.URL.Query().Get("role") != "admin" {
+				t.Error(r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"userId": 1, "username": "mim"}, {"userId": 3, "username": "zed"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		}
+	}))
+}
+
+/* ------------- tests ------------- */
+
+func TestSignetTestInvalidMode(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url=http://localhost:3000",
+		"--provider-url", "http://localhost:3002",
+		"--mode", "consumers",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --mode must be either spec or contracts"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestContractsMode(t *testing.T) {
+	broker, req := mockServerForGetContractsReq200OK(t)
+	defer broker.Close()
+
+	states := []string{}
+	provider := mockProviderForContracts(t, &states)
+	defer provider.Close()
+
+	junitPath := filepath.Join(t.TempDir(), "results.xml")
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url", broker.URL,
+		"--provider-url", provider.URL,
+		"--mode", "contracts",
+		"--environment", "production",
+		"--state-setup-url", provider.URL + "/_state",
+		"--report", "junit=" + junitPath,
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("fetches contracts for the provider and environment", func(t *testing.T) {
+		if req.URL.Path != "/api/contracts" || req.URL.Query().Get("provider") != "user_service" || req.URL.Query().Get("environment") != "production" {
+			t.Error(req.URL)
+		}
+	})
+
+	t.Run("sends provider states before and after each interaction", func(t *testing.T) {
+		if len(states) != 8 || states[0] != "setup: user 1 exists" || states[1] != "teardown: user 1 exists" {
+			t.Error(states)
+		}
+	})
+
+	t.Run("reports a test suite per consumer", func(t *testing.T) {
+		junitBytes, _ := os.ReadFile(junitPath)
+		junit := string(junitBytes)
+
+		if !strings.Contains(junit, `<testsuite name="service_1 -&gt; user_service" tests="2" failures="0"`) ||
+			!strings.Contains(junit, `<testsuite name="service_2 -&gt; user_service" tests="2" failures="1"`) {
+			t.Error(junit)
+		}
+	})
+
+	t.Run("fails when a consumer's contract is not satisfied", func(t *testing.T) {
+		expected := "Error: consumer contract verification failed for 1 of 2 consumers"
+		actual.startsWith(expected, t)
+	})
+
+	teardown()
+}
+
+func TestSignetTestContractsModeLocalContracts(t *testing.T) {
+	states := []string{}
+	provider := mockProviderForContracts(t, &states)
+	defer provider.Close()
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", provider.URL,
+		"--mode", "contracts",
+		"--contracts", "../data_test/contracts/service_1-user_service.json,../data_test/contracts/service_2-user_service.json",
+		"--state-setup-url", provider.URL + "/_state",
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("replays the local contracts without a broker", func(t *testing.T) {
+		if len(states) != 8 {
+			t.Error(states)
+		}
+	})
+
+	t.Run("fails when a consumer's contract is not satisfied", func(t *testing.T) {
+		expected := "Error: consumer contract verification failed for 1 of 2 consumers"
+		actual.startsWith(expected, t)
+	})
+
+	teardown()
+}
+
+func TestSignetTestContractsModeNoBrokerURL(t *testing.T) {
+	flags := []string{
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--mode", "contracts",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: No --broker-url was provided. This flag is required for --mode contracts, unless --contracts is set."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestContractsModeRejectsEnvironmentWithContracts(t *testing.T) {
+	flags := []string{
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--mode", "contracts",
+		"--environment", "production",
+		"--contracts", "../data_test/contracts/service_1-user_service.json",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --environment cannot be used together with --contracts, it only filters the contracts fetched from the broker"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestContractsModeRejectsConcurrency(t *testing.T) {
+	flags := []string{
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--mode", "contracts",
+		"--contracts", "../data_test/contracts/service_1-user_service.json",
+		"--concurrency", "4",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --concurrency cannot be used with --mode contracts"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestContractsModeChecksProvider(t *testing.T) {
+	flags := []string{
+		"--name", "order_service",
+		"--provider-url", "http://localhost:3002",
+		"--mode", "contracts",
+		"--contracts", "../data_test/contracts/service_1-user_service.json",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: contract ../data_test/contracts/service_1-user_service.json is for provider \"user_service\", not order_service"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestMatchResponse(t *testing.T) {
+	expected := map[string]interface{}{
+		"status":  float64(200),
+		"headers": map[string]interface{}{"Content-Type": "application/json"},
+		"body": map[string]interface{}{
+			"id":    "abc",
+			"count": float64(2),
+			"tags":  []interface{}{"a"},
+			"owner": map[string]interface{}{"name": "mim", "age": float64(30)},
+		},
+		"matchingRules": map[string]interface{}{
+			"body": map[string]interface{}{
+				"$.id":    map[string]interface{}{"matchers": []interface{}{map[string]interface{}{"match": "regex", "regex": "^[a-z]+$"}}},
+				"$.tags":  map[string]interface{}{"matchers": []interface{}{map[string]interface{}{"match": "type", "min": float64(1)}}},
+				"$.owner": map[string]interface{}{"matchers": []interface{}{map[string]interface{}{"match": "type"}}},
+			},
+		},
+	}
+	headers := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+
+	t.Run("matches by rules", func(t *testing.T) {
+		body := `{"id": "xyz", "count": 2, "tags": ["b", "c"], "owner": {"name": "bob", "age": 41}, "extra": true}`
+		mismatches := utils.MatchResponse(expected, 200, headers, []byte(body))
+
+		if len(mismatches) != 0 {
+			t.Error(mismatches)
+		}
+	})
+
+	t.Run("reports each mismatch", func(t *testing.T) {
+		body := `{"id": "XYZ", "count": 3, "tags": [], "owner": {"name": 7}}`
+		mismatches := utils.MatchResponse(expected, 500, headers, []byte(body))
+
+		expectedMismatches := []string{
+			"status: expected 200 but got 500",
+			"body $.count: expected 2 but got 3",
+			`body $.id: expected a value matching "^[a-z]+$" but got "XYZ"`,
+			`body $.owner: missing key "age"`,
+			`body $.owner.name: expected a value of type string but got 7`,
+			"body $.tags: expected at least 1 items but got 0",
+		}
+
+		if strings.Join(mismatches, "\n") != strings.Join(expectedMismatches, "\n") {
+			t.Error(mismatches)
+		}
+	})
+}
+
+func TestContractVerifierEncodesRequestBodies(t *testing.T) {
+	received := map[string]string{}
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			r.ParseForm()
+			received["form"] = r.PostForm.Get("username")
+		case "/avatars":
+			r.ParseMultipartForm(1024)
+			file, header, err := r.FormFile("avatar")
+			if err == nil {
+				fileBytes, _ := io.ReadAll(file)
+				received["multipart"] = header.Filename + ":" + string(fileBytes) + ":" + r.FormValue("userId")
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer provider.Close()
+
+	verifier := utils.ContractVerifier{ProviderURL: provider.URL}
+
+	formInteraction := map[string]interface{}{
+		"description": "POST /login 204",
+		"request": map[string]interface{}{
+			"method":  "POST",
+			"path":    "/login",
+			"headers": map[string]interface{}{"Content-Type": "application/x-www-form-urlencoded"},
+			"body":    map[string]interface{}{"username": "mim"},
+		},
+		"response": map[string]interface{}{"status": float64(204)},
+	}
+
+	multipartInteraction := map[string]interface{}{
+		"description": "POST /avatars 204",
+		"request": map[string]interface{}{
+			"method":  "POST",
+			"path":    "/avatars",
+			"headers": map[string]interface{}{"Content-Type": "multipart/form-data; boundary=recorded"},
+			"body": []interface{}{
+				map[string]interface{}{"name": "userId", "contentType": "text/plain", "body": "1"},
+				map[string]interface{}{"name": "avatar", "contentType": "image/png", "filename": "mim.png", "body": "iVBORw==", "bodyEncoding": "base64"},
+			},
+		},
+		"response": map[string]interface{}{"status": float64(204)},
+	}
+
+	for _, interaction := range []map[string]interface{}{formInteraction, multipartInteraction} {
+		result := verifier.VerifyInteraction("service_1", interaction)
+		if result.Status != "pass" {
+			t.Error(result.Failures)
+		}
+	}
+
+	if received["form"] != "mim" {
+		t.Error(received)
+	}
+
+	if received["multipart"] != "mim.png:\x89PNG:1" {
+		t.Error(received)
+	}
+}
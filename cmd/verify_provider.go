@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,15 +19,26 @@ import (
 const rwPermissions = 0666
 
 var providerURL string
+var localSpecPath string
+var noPublish bool
+var reports map[string]string
+var concurrency int
+var mode string
+var contractPaths []string
+var showCoverage bool
+var minCoverage float64
+var fuzz bool
+var dreddHooks utils.DreddHooks
 
 // abstract pkg fn's to enable mocking during testing
 var getNpmPkgRoot = utils.GetNpmPkgRoot
 var osWriteFile = os.WriteFile
+var runDredd = testProvider
 
 var testCmd = &cobra.Command{
 	Use:   "test",
 	Short: "test that a provider version correctly implements an OpenAPI spec",
-	Long: `test that a provider version correctly implements an OpenAPI spec
+	Long: `test that a provider version correctly implements an OpenAPI spec, or satisfies the contracts of its consumers
 	
 	flags:
 
@@ -37,66 +50,233 @@ var testCmd = &cobra.Command{
 	
 	-s --provider-url   the URL where the provider service is running
 	
-	-u --broker-url     the scheme, domain, and port where the Signet broker is being hosted
+	-u --broker-url     the scheme, domain, and port where the Signet broker is being hosted (optional with --spec and --no-publish)
+
+	--mode              spec to verify the provider against its OpenAPI spec, or contracts to replay its consumers' contracts (optional, defaults to spec)
+
+	-e --environment    only verify the contracts of consumers deployed to this environment (optional, for --mode contracts)
+
+	--contracts         local consumer contract files to replay instead of the contracts in the broker, ex. --contracts service_1.json,service_2.json (optional, for --mode contracts)
+
+	--spec              a local OpenAPI spec (JSON or YAML) to test against, instead of the latest spec from the broker (optional)
+
+	--no-publish        do not publish successful verification results to the broker (optional)
+
+	-c --concurrency    the number of operations to verify in parallel (optional, defaults to 1)
+
+	--report            write verification reports, as format=path (optional, formats are junit, json, and coverage, ex. --report junit=results.xml,json=results.json)
+
+	--coverage          print which responses in the spec were verified (optional)
+
+	--min-coverage      fail if less than this percentage of the responses in the spec were verified (optional, ex. 80)
+
+	--work-dir          the directory where a temporary directory is made for the spec, hooks, and results (optional, defaults to the system temp directory)
+
+	--keep-artifacts    do not remove the temporary directory when the test finishes (optional)
+
+	--fuzz              also send invalid requests generated from the spec's request schemas, which must be rejected with a documented 4xx response (optional)
+
+	--state-setup-url   a URL on the provider that is sent the provider state before and after each interaction (optional)
+
+	--before-hook       a shell command to run before each interaction (optional)
+
+	--after-hook        a shell command to run after each interaction (optional)
+
+	-H --request-header a header added to every request, as Name=value (optional, can be repeated)
+
+	--request-filter    a shell command that is given each request as JSON on stdin, and prints the request to send on stdout (optional)
 	
 	-i --ignore-config  ingore .signetrc.yaml file if it exists (optional)
 	`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name = viper.GetString("test.name")
 		providerURL = viper.GetString("test.provider-url")
+		localSpecPath = viper.GetString("test.spec")
+		noPublish = viper.GetBool("test.no-publish")
+		reports = viper.GetStringMapString("test.report")
+		concurrency = viper.GetInt("test.concurrency")
+		mode = viper.GetString("test.mode")
+		environment = viper.GetString("test.environment")
+		contractPaths = viper.GetStringSlice("test.contracts")
+		showCoverage = viper.GetBool("test.coverage")
+		minCoverage = viper.GetFloat64("test.min-coverage")
+		fuzz = viper.GetBool("test.fuzz")
+		workDir = viper.GetString("test.work-dir")
+		keepArtifacts = viper.GetBool("test.keep-artifacts")
+		dreddHooks = utils.DreddHooks{
+			StateSetupURL:  viper.GetString("test.state-setup-url"),
+			BeforeHook:     viper.GetString("test.before-hook"),
+			AfterHook:      viper.GetString("test.after-hook"),
+			RequestHeaders: viper.GetStringMapString("test.request-headers"),
+			RequestFilter:  viper.GetString("test.request-filter"),
+		}
 
-		err := validateTestFlags(brokerURL, name, version, providerURL)
+		var err error
+		if mode == contractsMode {
+			err = validateContractsFlags(brokerURL, name, providerURL)
+		} else {
+			err = validateTestFlags(brokerURL, name, version, providerURL, localSpecPath, noPublish)
+		}
 		if err != nil {
 			return err
 		}
 
-		spec, err := client.GetLatestSpec(brokerURL, name)
+		err = validateReportFlags(reports)
 		if err != nil {
 			return err
 		}
 
+		if concurrency < 1 {
+			return errors.New("--concurrency must be at least 1")
+		}
+
+		err = validateCoverageFlags(showCoverage, minCoverage, mode)
+		if err != nil {
+			return err
+		}
+
+		switch mode {
+		case specMode:
+		case contractsMode:
+			return verifyConsumerContracts(cmd)
+		default:
+			return errors.New("--mode must be either spec or contracts")
+		}
+
 		signetRoot, err := getNpmPkgRoot()
 		if err != nil {
 			return err
 		}
-		specPath := signetRoot + "/specs/spec.json"
 		dreddPath := signetRoot + "/node_modules/dredd"
 
-		err = osWriteFile(specPath, spec, rwPermissions)
+		specsDir, cleanupWorkDir, err := createWorkDir(workDir, "signet-test-")
 		if err != nil {
-			return errors.New("Failed to write specs/spec file: " + err.Error())
+			return err
 		}
+		defer cleanupWorkDir()
 
-		testOutput, err := testProvider(dreddPath, specPath, providerURL)
+		specPath := localSpecPath
+		if len(localSpecPath) == 0 {
+			specPath = specsDir + "/spec.json"
 
+			spec, err := client.GetLatestSpec(brokerURL, name)
+			if err != nil {
+				return err
+			}
+
+			err = osWriteFile(specPath, spec, rwPermissions)
+			if err != nil {
+				return errors.New("Failed to write spec file: " + err.Error())
+			}
+		}
+
+		// results are recorded for reports, coverage, and the summary of failing operations
+		checkCoverage := showCoverage || minCoverage != 0 || len(reports["coverage"]) != 0
+		if len(reports) != 0 || !noPublish || checkCoverage || fuzz {
+			dreddHooks.ResultsPath = specsDir + "/results.json"
+		}
+
+		var run verificationRun
+		if concurrency > 1 {
+			run, err = runConcurrentVerification(specsDir, dreddPath, specPath, providerURL, dreddHooks, concurrency)
+		} else {
+			run, err = runVerification(specsDir, dreddPath, specPath, providerURL, dreddHooks, "hooks.js")
+		}
 		if err != nil {
+			return err
+		}
+
+		if fuzz {
+			fuzzRun, err := runFuzzVerification(cmd, specPath, providerURL, dreddHooks)
+			if err != nil {
+				return err
+			}
+			run = combineVerificationRuns([]verificationRun{run, fuzzRun})
+		}
+
+		if run.resultsErr != nil && (len(reports) != 0 || checkCoverage) {
+			return run.resultsErr
+		}
+		results := run.results
+		testOutput := run.output
+
+		if len(reports) != 0 {
+			err = writeVerificationReports(results, reports, name, version)
+			if err != nil {
+				return err
+			}
+		}
+
+		var coverage utils.CoverageReport
+		if checkCoverage {
+			coverage, err = reportSpecCoverage(specPath, results, reports["coverage"])
+			if err != nil {
+				return err
+			}
+		}
+
+		if run.testErr != nil {
 			fmt.Println(colorRed + "FAIL" + colorReset + ": Provider test failed - the provider service does not correctly implement the API spec")
 			fmt.Println()
 			fmt.Println("Breakdown of interactions:")
 			testOutput = utils.SliceOutNodeWarnings(testOutput)
 			fmt.Println(testOutput)
-		} else {
-			fmt.Println(colorGreen + "PASS" + colorReset + ": Provider test passed - the provider service correctly implements the API spec")
-			fmt.Println()
-			fmt.Println("Informing the Signet broker of successful verification...")
 
-			err = utils.PublishProvider(specPath, brokerURL, name, version, branch)
-			if err != nil {
-				return err
+			failingOperations := utils.FailingOperations(results)
+			if len(failingOperations) == 0 {
+				failingOperations = utils.FailingOperationsFromOutput(testOutput)
+			}
+
+			cmd.Println("Failing operations:")
+			for _, operation := range failingOperations {
+				cmd.Println("  " + operation)
 			}
 
-			fmt.Println("Verification results published to Signet broker")
+			if !noPublish {
+				// publishing the spec marks the version as verified, so a failed run leaves the broker untouched
+				cmd.Println("The spec was not published to the Signet broker, so this version remains unverified")
+			}
+
+			// the failure was already explained, so usage is not printed
+			cmd.SilenceUsage = true
+			return errors.New("provider verification failed with " + strconv.Itoa(len(failingOperations)) + " failing operations")
+		}
+
+		if coverage.Percent < minCoverage {
+			cmd.SilenceUsage = true
+			return errors.New("spec coverage of " + formatPercent(coverage.Percent) + " is below --min-coverage of " + formatPercent(minCoverage))
+		}
+
+		fmt.Println(colorGreen + "PASS" + colorReset + ": Provider test passed - the provider service correctly implements the API spec")
+		fmt.Println()
+
+		if noPublish {
+			fmt.Println("Verification results were not published to Signet broker (--no-publish)")
+			return nil
+		}
+
+		fmt.Println("Informing the Signet broker of successful verification...")
+
+		err = utils.PublishProvider(specPath, brokerURL, name, version, branch)
+		if err != nil {
+			return err
 		}
 
+		fmt.Println("Verification results published to Signet broker")
+
 		return nil
 	},
 }
 
-func validateTestFlags(brokerURL, name, version, providerURL string) error {
-	if len(brokerURL) == 0 {
+func validateTestFlags(brokerURL, name, version, providerURL, localSpecPath string, noPublish bool) error {
+	if len(brokerURL) == 0 && len(localSpecPath) == 0 {
 		return errors.New("No --broker-url was provided. This is a required flag.")
 	}
 
+	if len(brokerURL) == 0 && !noPublish {
+		return errors.New("No --broker-url was provided. This flag is required unless --no-publish is set.")
+	}
+
 	if len(name) == 0 {
 		return errors.New("No --name was provided. This is a required flag.")
 	}
@@ -113,11 +293,88 @@ func validateTestFlags(brokerURL, name, version, providerURL string) error {
 		return errors.New("No --provider-url was provided. This is a required flag.")
 	}
 
+	if len(localSpecPath) != 0 {
+		ext := filepath.Ext(localSpecPath)
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			return errors.New("--spec must be an OpenAPI spec in a .json, .yaml, or .yml file")
+		}
+
+		_, err := os.Stat(localSpecPath)
+		if err != nil {
+			return errors.New("Failed to read --spec: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func validateReportFlags(reports map[string]string) error {
+	for format, reportPath := range reports {
+		if !containsString(utils.ReportFormats, format) {
+			return errors.New("--report format " + format + " is not supported. Supported formats are junit, json, and coverage.")
+		}
+
+		if len(reportPath) == 0 {
+			return errors.New("--report " + format + " has no path")
+		}
+	}
+
+	return nil
+}
+
+func writeVerificationReports(results []utils.VerificationResult, reports map[string]string, name, version string) error {
+	if version == "" || version == "auto" {
+		version, _ = utils.SetVersionToGitSha(version)
+	}
+	report := utils.CreateVerificationReport(results, name, version)
+
+	for _, format := range utils.ReportFormats {
+		reportPath, ok := reports[format]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch format {
+		case "junit":
+			err = utils.WriteJUnitReport(report, reportPath)
+		case "json":
+			err = utils.WriteJSONReport(report, reportPath)
+		default:
+			// the coverage report is written with the spec coverage
+			continue
+		}
+		if err != nil {
+			return errors.New("Failed to write " + format + " report: " + err.Error())
+		}
+
+		fmt.Println("Wrote " + format + " report to " + reportPath)
+	}
+
 	return nil
 }
 
-func testProvider(dreddPath, specPath, providerURL string) (string, error) {
-	testCmd := exec.Command("npx", dreddPath, specPath, providerURL, "--loglevel=error")
+func writeDreddHooks(hookfilePath string, hooks utils.DreddHooks) error {
+	script, err := utils.CreateDreddHooks(hooks)
+	if err != nil {
+		return errors.New("Failed to generate dredd hooks: " + err.Error())
+	}
+
+	err = osWriteFile(hookfilePath, script, rwPermissions)
+	if err != nil {
+		return errors.New("Failed to write hooks file: " + err.Error())
+	}
+
+	return nil
+}
+
+func testProvider(dreddPath, specPath, providerURL, hookfilePath string) (string, error) {
+	dreddArgs := []string{dreddPath, specPath, providerURL, "--loglevel=error"}
+	if len(hookfilePath) != 0 {
+		dreddArgs = append(dreddArgs, "--hookfiles="+hookfilePath)
+	}
+
+	testCmd := exec.Command("npx", dreddArgs...)
 	stdoutStderr, err := testCmd.CombinedOutput()
 	testOutput := string(stdoutStderr)
 
@@ -135,8 +392,42 @@ func init() {
 	testCmd.Flags().StringVarP(&version, "version", "v", "auto", "The version of the service which was deployed")
 	testCmd.Flags().StringVarP(&branch, "branch", "b", "", "Version control branch (optional)")
 	testCmd.Flags().StringVarP(&providerURL, "provider-url", "s", "", "The URL where the provider service is running")
+	testCmd.Flags().StringVar(&mode, "mode", specMode, "spec to verify the provider against its OpenAPI spec, or contracts to replay its consumers' contracts (optional)")
+	testCmd.Flags().StringVarP(&environment, "environment", "e", "", "only verify the contracts of consumers deployed to this environment (optional, for --mode contracts)")
+	testCmd.Flags().StringSliceVar(&contractPaths, "contracts", nil, "local consumer contract files to replay instead of the contracts in the broker (optional, for --mode contracts)")
+	testCmd.Flags().StringVar(&localSpecPath, "spec", "", "a local OpenAPI spec (JSON or YAML) to test against, instead of the latest spec from the broker (optional)")
+	testCmd.Flags().BoolVar(&noPublish, "no-publish", false, "do not publish successful verification results to the broker (optional)")
+	testCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 1, "the number of operations to verify in parallel (optional, defaults to 1)")
+	testCmd.Flags().StringToStringVar(&reports, "report", nil, "write verification reports, as format=path (optional, formats are junit, json, and coverage)")
+	testCmd.Flags().BoolVar(&showCoverage, "coverage", false, "print which responses in the spec were verified (optional)")
+	testCmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "fail if less than this percentage of the responses in the spec were verified (optional)")
+	testCmd.Flags().StringVar(&workDir, "work-dir", "", "the directory where a temporary directory is made for the spec, hooks, and results (optional)")
+	testCmd.Flags().BoolVar(&keepArtifacts, "keep-artifacts", false, "do not remove the temporary directory when the test finishes (optional)")
+	testCmd.Flags().BoolVar(&fuzz, "fuzz", false, "also send invalid requests generated from the spec's request schemas (optional)")
+	testCmd.Flags().StringVar(&dreddHooks.StateSetupURL, "state-setup-url", "", "a URL on the provider that is sent the provider state before and after each interaction (optional)")
+	testCmd.Flags().StringVar(&dreddHooks.BeforeHook, "before-hook", "", "a shell command to run before each interaction (optional)")
+	testCmd.Flags().StringVar(&dreddHooks.AfterHook, "after-hook", "", "a shell command to run after each interaction (optional)")
+	testCmd.Flags().StringToStringVarP(&dreddHooks.RequestHeaders, "request-header", "H", nil, "a header added to every request, as Name=value (optional, can be repeated)")
+	testCmd.Flags().StringVar(&dreddHooks.RequestFilter, "request-filter", "", "a shell command that is given each request as JSON on stdin, and prints the request to send on stdout (optional)")
 	testCmd.Flags().Lookup("branch").NoOptDefVal = "auto"
 
 	viper.BindPFlag("test.name", testCmd.Flags().Lookup("name"))
 	viper.BindPFlag("test.provider-url", testCmd.Flags().Lookup("provider-url"))
+	viper.BindPFlag("test.mode", testCmd.Flags().Lookup("mode"))
+	viper.BindPFlag("test.environment", testCmd.Flags().Lookup("environment"))
+	viper.BindPFlag("test.contracts", testCmd.Flags().Lookup("contracts"))
+	viper.BindPFlag("test.spec", testCmd.Flags().Lookup("spec"))
+	viper.BindPFlag("test.no-publish", testCmd.Flags().Lookup("no-publish"))
+	viper.BindPFlag("test.concurrency", testCmd.Flags().Lookup("concurrency"))
+	viper.BindPFlag("test.report", testCmd.Flags().Lookup("report"))
+	viper.BindPFlag("test.coverage", testCmd.Flags().Lookup("coverage"))
+	viper.BindPFlag("test.min-coverage", testCmd.Flags().Lookup("min-coverage"))
+	viper.BindPFlag("test.work-dir", testCmd.Flags().Lookup("work-dir"))
+	viper.BindPFlag("test.keep-artifacts", testCmd.Flags().Lookup("keep-artifacts"))
+	viper.BindPFlag("test.fuzz", testCmd.Flags().Lookup("fuzz"))
+	viper.BindPFlag("test.state-setup-url", testCmd.Flags().Lookup("state-setup-url"))
+	viper.BindPFlag("test.before-hook", testCmd.Flags().Lookup("before-hook"))
+	viper.BindPFlag("test.after-hook", testCmd.Flags().Lookup("after-hook"))
+	viper.BindPFlag("test.request-headers", testCmd.Flags().Lookup("request-header"))
+	viper.BindPFlag("test.request-filter", testCmd.Flags().Lookup("request-filter"))
 }
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+// the outcome of one or more dredd runs
+type verificationRun struct {
+	output     string
+	results    []utils.VerificationResult
+	resultsErr error
+	testErr    error
+}
+
+// runVerification verifies the spec at specPath with a single dredd process
+func runVerification(specsDir, dreddPath, specPath, providerURL string, hooks utils.DreddHooks, hookfileName string) (verificationRun, error) {
+	var run verificationRun
+
+	if len(hooks.ResultsPath) != 0 {
+		os.Remove(hooks.ResultsPath)
+	}
+
+	hookfilePath := ""
+	if !hooks.IsEmpty() {
+		hookfilePath = specsDir + "/" + hookfileName
+		err := writeDreddHooks(hookfilePath, hooks)
+		if err != nil {
+			return run, err
+		}
+	}
+
+	run.output, run.testErr = runDredd(dreddPath, specPath, providerURL, hookfilePath)
+
+	if len(hooks.ResultsPath) != 0 {
+		run.results, run.resultsErr = utils.LoadVerificationResults(hooks.ResultsPath)
+	}
+
+	return run, nil
+}
+
+/*
+runConcurrentVerification verifies each operation in the spec with its own
+dredd process, running up to concurrency processes at once. Operations in a
+serial group (x-signet-serial) are verified one after another. The output and
+results are combined in operation order, so they do not depend on which
+operations finished first.
+*/
+func runConcurrentVerification(specsDir, dreddPath, specPath, providerURL string, hooks utils.DreddHooks, concurrency int) (verificationRun, error) {
+	spec, err := utils.LoadOpenAPISpec(specPath)
+	if err != nil {
+		return verificationRun{}, err
+	}
+
+	batches := utils.BatchSpecOperations(utils.SpecOperations(spec))
+
+	// each operation gets an index in the combined order, which also names its files
+	batchOffsets := []int{}
+	operationCount := 0
+	for _, batch := range batches {
+		batchOffsets = append(batchOffsets, operationCount)
+		operationCount += len(batch)
+	}
+
+	runs := make([]verificationRun, operationCount)
+	runErrs := make([]error, len(batches))
+
+	batchIndexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range batchIndexes {
+				for j, op := range batches[i] {
+					index := batchOffsets[i] + j
+					runs[index], runErrs[i] = runOperationVerification(specsDir, dreddPath, providerURL, hooks, spec, op, index)
+					if runErrs[i] != nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range batches {
+		batchIndexes <- i
+	}
+	close(batchIndexes)
+	wg.Wait()
+
+	for _, err := range runErrs {
+		if err != nil {
+			return verificationRun{}, err
+		}
+	}
+
+	return combineVerificationRuns(runs), nil
+}
+
+func runOperationVerification(specsDir, dreddPath, providerURL string, hooks utils.DreddHooks, spec map[string]interface{}, op utils.SpecOperation, index int) (verificationRun, error) {
+	suffix := "-" + strconv.Itoa(index)
+
+	opSpec, err := json.Marshal(utils.SpecForOperations(spec, []utils.SpecOperation{op}))
+	if err != nil {
+		return verificationRun{}, err
+	}
+
+	opSpecPath := specsDir + "/spec" + suffix + ".json"
+	err = osWriteFile(opSpecPath, opSpec, rwPermissions)
+	if err != nil {
+		return verificationRun{}, errors.New("Failed to write spec file for " + op.String() + ": " + err.Error())
+	}
+
+	if len(hooks.ResultsPath) != 0 {
+		hooks.ResultsPath = specsDir + "/results" + suffix + ".json"
+	}
+
+	return runVerification(specsDir, dreddPath, opSpecPath, providerURL, hooks, "hooks"+suffix+".js")
+}
+
+func combineVerificationRuns(runs []verificationRun) verificationRun {
+	var combined verificationRun
+	outputs := []string{}
+
+	for _, run := range runs {
+		outputs = append(outputs, strings.TrimRight(run.output, "\n"))
+		combined.results = append(combined.results, run.results...)
+
+		if combined.resultsErr == nil {
+			combined.resultsErr = run.resultsErr
+		}
+		if combined.testErr == nil {
+			combined.testErr = run.testErr
+		}
+	}
+
+	combined.output = strings.Join(outputs, "\n") + "\n"
+	return combined
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/* ------------- helpers ------------- */
+
+// mocks dredd by recording one passing result for the operation in the spec it is given
+func mockRunDreddPerOperation(t *testing.T, onRun func(op string)) func(string, string, string, string) (string, error) {
+	return func(dreddPath, specPath, providerURL, hookfilePath string) (string, error) {
+		spec, err := utils.LoadOpenAPISpec(specPath)
+		if err != nil {
+			t.Error(err)
+			return "", err
+		}
+
+		op := utils.SpecOperations(spec)[0].String()
+		onRun(op)
+
+		resultsPath := strings.Replace(strings.Replace(hookfilePath, "hooks-", "results-", 1), ".js", ".json", 1)
+		results := `[{"name": "` + op + `", "operation": "` + op + `", "status": "pass"}]`
+		os.WriteFile(resultsPath, []byte(results), 0644)
+
+		return "pass: " + op + "\n", nil
+	}
+}
+
+/* ------------- tests ------------- */
+
+func TestBatchSpecOperations(t *testing.T) {
+	spec, err := utils.LoadOpenAPISpec("../data_test/serial-spec.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batches := utils.BatchSpecOperations(utils.SpecOperations(spec))
+	actual := [][]string{}
+	for _, batch := range batches {
+		ops := []string{}
+		for _, op := range batch {
+			ops = append(ops, op.String())
+		}
+		actual = append(actual, ops)
+	}
+
+	expected := [][]string{
+		{"GET /health"},
+		{"GET /users"},
+		{"POST /users", "GET /users/{id}", "DELETE /users/{id}"},
+	}
+
+	if len(actual) != len(expected) {
+		t.Fatal(actual)
+	}
+
+	for i := range expected {
+		if strings.Join(actual[i], ",") != strings.Join(expected[i], ",") {
+			t.Error(actual)
+		}
+	}
+}
+
+func TestSpecForOperationsKeepsPathParameters(t *testing.T) {
+	spec, _ := utils.LoadOpenAPISpec("../data_test/serial-spec.yaml")
+	ops := utils.SpecOperations(spec)
+
+	opSpec := utils.SpecForOperations(spec, ops[len(ops)-1:])
+	paths := opSpec["paths"].(map[string]interface{})
+	pathItem := paths["/users/{id}"].(map[string]interface{})
+
+	if len(paths) != 1 || len(pathItem) != 2 || pathItem["parameters"] == nil || pathItem["delete"] == nil {
+		t.Error(paths)
+	}
+
+	if opSpec["components"] == nil {
+		t.Error("expected components to be kept")
+	}
+}
+
+func TestConcurrentVerification(t *testing.T) {
+	realRunDredd := runDredd
+	defer func() { runDredd = realRunDredd }()
+
+	var mu sync.Mutex
+	serialRunning := 0
+	serialOverlapped := false
+
+	runDredd = mockRunDreddPerOperation(t, func(op string) {
+		serial := strings.HasPrefix(op, "POST") || strings.Contains(op, "{id}")
+
+		mu.Lock()
+		if serial {
+			serialRunning++
+			serialOverlapped = serialOverlapped || serialRunning > 1
+		}
+		mu.Unlock()
+
+		// the first operations finish last
+		if op == "GET /health" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		if serial {
+			serialRunning--
+		}
+		mu.Unlock()
+	})
+
+	specsDir := t.TempDir()
+	hooks := utils.DreddHooks{ResultsPath: filepath.Join(specsDir, "results.json")}
+
+	run, err := runConcurrentVerification(specsDir, "dredd", "../data_test/serial-spec.yaml", "http://localhost:3002", hooks, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("results are in operation order", func(t *testing.T) {
+		expected := []string{"GET /health", "GET /users", "POST /users", "GET /users/{id}", "DELETE /users/{id}"}
+
+		if len(run.results) != len(expected) {
+			t.Fatal(run.results)
+		}
+
+		for i, result := range run.results {
+			if result.Name != expected[i] {
+				t.Error(run.results)
+			}
+		}
+	})
+
+	t.Run("output is in operation order", func(t *testing.T) {
+		if !strings.HasPrefix(run.output, "pass: GET /health\npass: GET /users\npass: POST /users\n") {
+			t.Error(run.output)
+		}
+	})
+
+	t.Run("serial operations never run at the same time", func(t *testing.T) {
+		if serialOverlapped {
+			t.Error("operations in the same x-signet-serial group overlapped")
+		}
+	})
+}
+
+func TestSignetTestConcurrencyMustBePositive(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url=http://localhost:3000",
+		"--provider-url", "http://localhost:3002",
+		"--concurrency", "0",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --concurrency must be at least 1"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
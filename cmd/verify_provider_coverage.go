@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+func validateCoverageFlags(showCoverage bool, minCoverage float64, mode string) error {
+	if minCoverage < 0 || minCoverage > 100 {
+		return errors.New("--min-coverage must be a percentage between 0 and 100")
+	}
+
+	if (showCoverage || minCoverage != 0) && mode != specMode {
+		return errors.New("--coverage and --min-coverage can only be used with --mode spec")
+	}
+
+	return nil
+}
+
+/*
+reportSpecCoverage prints which responses in the spec were exercised by the
+verification, and writes the coverage report if one was requested.
+*/
+func reportSpecCoverage(specPath string, results []utils.VerificationResult, reportPath string) (utils.CoverageReport, error) {
+	spec, err := utils.LoadOpenAPISpec(specPath)
+	if err != nil {
+		return utils.CoverageReport{}, err
+	}
+
+	coverage := utils.SpecCoverage(spec, results)
+
+	fmt.Println("Spec coverage: " + strconv.Itoa(coverage.Covered) + " of " + strconv.Itoa(coverage.Total) + " responses were verified (" + formatPercent(coverage.Percent) + ")")
+	for _, entry := range coverage.Entries {
+		if entry.Covered {
+			fmt.Println("  " + colorGreen + "covered" + colorReset + "      " + entry.String())
+		} else {
+			fmt.Println("  " + colorRed + "not covered" + colorReset + "  " + entry.String())
+		}
+	}
+	fmt.Println()
+
+	if len(reportPath) != 0 {
+		err = utils.WriteCoverageReport(coverage, reportPath)
+		if err != nil {
+			return coverage, errors.New("Failed to write coverage report: " + err.Error())
+		}
+		fmt.Println("Wrote coverage report to " + reportPath)
+	}
+
+	return coverage, nil
+}
+
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 1, 64) + "%"
+}
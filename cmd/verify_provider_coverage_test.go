@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+func TestSpecCoverage(t *testing.T) {
+	spec, err := utils.LoadOpenAPISpec("../data_test/serial-spec.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []utils.VerificationResult{
+		{Operation: "GET /health", Status: "pass", Expected: &utils.ReportedMessage{StatusCode: 200}},
+		{Operation: "POST /users", Status: "fail", Expected: &utils.ReportedMessage{StatusCode: 201}},
+		{Operation: "DELETE /users/{id}", Status: "skip", Expected: &utils.ReportedMessage{StatusCode: 204}},
+	}
+
+	coverage := utils.SpecCoverage(spec, results)
+
+	t.Run("lists every documented response", func(t *testing.T) {
+		if coverage.Total != 5 || len(coverage.Entries) != 5 {
+			t.Error(coverage)
+		}
+	})
+
+	t.Run("failed results count, skipped results do not", func(t *testing.T) {
+		covered := []string{}
+		for _, entry := range coverage.Entries {
+			if entry.Covered {
+				covered = append(covered, entry.String())
+			}
+		}
+
+		if len(covered) != 2 || covered[0] != "GET /health 200" || covered[1] != "POST /users 201" {
+			t.Error(covered)
+		}
+
+		if coverage.Percent != 40 {
+			t.Error(coverage.Percent)
+		}
+	})
+}
+
+func TestSignetTestMinCoverageOutOfRange(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url=http://localhost:3000",
+		"--provider-url", "http://localhost:3002",
+		"--min-coverage", "120",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --min-coverage must be a percentage between 0 and 100"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestBelowMinCoverage(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realRunDredd := runDredd
+	defer func() {
+		getNpmPkgRoot = realGetNpmPkgRoot
+		runDredd = realRunDredd
+	}()
+
+	signetRoot := t.TempDir()
+	getNpmPkgRoot = func() (string, error) { return signetRoot, nil }
+
+	runDredd = func(dreddPath, specPath, providerURL, hookfile string) (string, error) {
+		results := `[{"name": "GET /health", "operation": "GET /health", "status": "pass", "expected": {"statusCode": 200}}]`
+		os.WriteFile(filepath.Join(filepath.Dir(hookfile), "results.json"), []byte(results), 0644)
+		return "pass: GET (200) /health duration: 5ms\n", nil
+	}
+
+	coveragePath := filepath.Join(t.TempDir(), "coverage.json")
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--spec", "../data_test/serial-spec.yaml",
+		"--no-publish",
+		"--min-coverage", "50",
+		"--report", "coverage=" + coveragePath,
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("writes the coverage report", func(t *testing.T) {
+		var report utils.CoverageReport
+		reportBytes, _ := os.ReadFile(coveragePath)
+		err := json.Unmarshal(reportBytes, &report)
+
+		if err != nil || report.Covered != 1 || report.Total != 5 {
+			t.Error(err, report)
+		}
+	})
+
+	t.Run("returns an error", func(t *testing.T) {
+		expected := "Error: spec coverage of 20.0% is below --min-coverage of 50.0%"
+		actual.startsWith(expected, t)
+	})
+
+	teardown()
+}
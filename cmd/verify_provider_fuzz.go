@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+/*
+runFuzzVerification sends the fuzz cases generated from the spec's request
+schemas to the provider. The output lists each case in the same format as
+dredd, so that fuzz failures are reported alongside the spec verification.
+*/
+func runFuzzVerification(cmd *cobra.Command, specPath, providerURL string, hooks utils.DreddHooks) (verificationRun, error) {
+	var run verificationRun
+
+	spec, err := utils.LoadOpenAPISpec(specPath)
+	if err != nil {
+		return run, err
+	}
+
+	verifier := utils.FuzzVerifier{ProviderURL: providerURL, Hooks: hooks}
+	run.results = verifier.VerifySpec(spec)
+
+	var output strings.Builder
+	failed := 0
+	for _, result := range run.results {
+		output.WriteString(result.Status + ": " + result.Name + " duration: " + strconv.FormatInt(result.DurationMs, 10) + "ms\n")
+		if result.Status != "fail" {
+			continue
+		}
+
+		failed++
+		for _, failure := range result.Failures {
+			output.WriteString("  - " + failure + "\n")
+		}
+	}
+	run.output = output.String()
+
+	cmd.Println("Fuzz testing: " + strconv.Itoa(len(run.results)-failed) + " of " + strconv.Itoa(len(run.results)) + " generated requests were handled correctly")
+	cmd.Println()
+
+	if failed != 0 {
+		run.testErr = errors.New(strconv.Itoa(failed) + " fuzz cases failed")
+	}
+
+	return run, nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	utils "github.com/signet-framework/signet-cli/utils"
+)
+
+func TestFuzzCases(t *testing.T) {
+	spec, err := utils.LoadOpenAPISpec("../data_test/fuzz-spec.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]utils.FuzzCase{}
+	for _, op := range utils.SpecOperations(spec) {
+		for _, fuzzCase := range utils.FuzzCases(spec, op) {
+			cases[op.String()+" "+fuzzCase.Description] = fuzzCase
+		}
+	}
+
+	t.Run("missing required query parameter", func(t *testing.T) {
+		fuzzCase, ok := cases["GET /users query parameter limit: missing required parameter"]
+		if !ok || !fuzzCase.Invalid || fuzzCase.Request.URI != "/users" {
+			t.Error(fuzzCase, fuzzCase.Request)
+		}
+	})
+
+	t.Run("out of range query parameter", func(t *testing.T) {
+		fuzzCase, ok := cases["GET /users query parameter limit: above maximum (101)"]
+		if !ok || !fuzzCase.Invalid || fuzzCase.Request.URI != "/users?limit=101" {
+			t.Error(fuzzCase, fuzzCase.Request)
+		}
+	})
+
+	t.Run("missing required body field", func(t *testing.T) {
+		fuzzCase, ok := cases["POST /users body field userId: missing required field"]
+		if !ok || !fuzzCase.Invalid {
+			t.Fatal(fuzzCase)
+		}
+
+		var body map[string]interface{}
+		json.Unmarshal([]byte(fuzzCase.Request.Body), &body)
+		if _, hasUserID := body["userId"]; hasUserID || body["username"] != "mim" {
+			t.Error(fuzzCase.Request.Body)
+		}
+	})
+
+	t.Run("wrong types and boundaries", func(t *testing.T) {
+		expected := []string{
+			"POST /users body field userId: wrong type (string instead of integer)",
+			"POST /users body field userId: below minimum (0)",
+			"POST /users body field username: shorter than minLength (0 characters)",
+			"POST /users body field username: longer than maxLength (21 characters)",
+			"POST /users body field role: not in enum",
+			"POST /users body: wrong type (string instead of object)",
+		}
+		for _, description := range expected {
+			if fuzzCase, ok := cases[description]; !ok || !fuzzCase.Invalid {
+				t.Error(description, fuzzCase)
+			}
+		}
+	})
+
+	t.Run("oversized strings only need to be handled", func(t *testing.T) {
+		fuzzCase, ok := cases["POST /users body field bio: oversized string (65536 characters)"]
+		if !ok || fuzzCase.Invalid {
+			t.Error(fuzzCase)
+		}
+	})
+}
+
+func TestSignetTestFuzz(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realRunDredd := runDredd
+	defer func() {
+		getNpmPkgRoot = realGetNpmPkgRoot
+		runDredd = realRunDredd
+	}()
+
+	signetRoot := t.TempDir()
+	getNpmPkgRoot = func() (string, error) { return signetRoot, nil }
+
+	runDredd = func(dreddPath, specPath, providerURL, hookfile string) (string, error) {
+		os.WriteFile(filepath.Join(filepath.Dir(hookfile), "results.json"), []byte("[]"), 0644)
+		return "pass: GET (200) /users duration: 5ms\n", nil
+	}
+
+	// rejects every generated request, but crashes on long bios
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if bio, ok := body["bio"].(string); ok && len(bio) > 1000 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer provider.Close()
+
+	reportPath := filepath.Join(t.TempDir(), "results.json")
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", provider.URL,
+		"--spec", "../data_test/fuzz-spec.yaml",
+		"--no-publish",
+		"--fuzz",
+		"--report", "json=" + reportPath,
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("reports fuzz cases alongside the verification results", func(t *testing.T) {
+		var report utils.VerificationReport
+		reportBytes, _ := os.ReadFile(reportPath)
+		json.Unmarshal(reportBytes, &report)
+
+		if report.Failed != 1 || report.Passed == 0 {
+			t.Fatal(report.Passed, report.Failed)
+		}
+
+		for _, result := range report.Results {
+			if result.Status != "fail" {
+				continue
+			}
+
+			expected := "/users > POST > fuzz > body field bio: oversized string (65536 characters)"
+			if result.Name != expected || result.Failures[0] != "expected a documented 4xx response, but got 500" {
+				t.Error(result.Name, result.Failures)
+			}
+		}
+	})
+
+	t.Run("prints a summary of the fuzz cases", func(t *testing.T) {
+		actual.contains("Fuzz testing: 18 of 19 generated requests were handled correctly", t)
+	})
+
+	t.Run("lists the failing fuzz case", func(t *testing.T) {
+		actual.contains("Failing operations:\n  /users > POST > fuzz > body field bio: oversized string (65536 characters)", t)
+	})
+
+	t.Run("returns an error", func(t *testing.T) {
+		actual.contains("Error: provider verification failed with 1 failing operations", t)
+	})
+
+	teardown()
+}
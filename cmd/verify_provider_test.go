@@ -2,8 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	utils "github.com/signet-framework/signet-cli/utils"
@@ -60,6 +66,55 @@ func TestSignetTestNoProviderURL(t *testing.T) {
 	teardown()
 }
 
+func TestSignetTestLocalSpecWithoutBrokerURL(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	defer func() { getNpmPkgRoot = realGetNpmPkgRoot }()
+
+	getNpmPkgRoot = func() (string, error) { return "", errors.New("stop this test here") }
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--spec", "../data_test/api-spec.yaml",
+		"--no-publish",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: stop this test here"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestLocalSpecPublishRequiresBrokerURL(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--spec", "../data_test/api-spec.yaml",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: No --broker-url was provided. This flag is required unless --no-publish is set."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestSignetTestLocalSpecMustBeOpenAPI(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--provider-url", "http://localhost:3002",
+		"--spec", "../data_test/schema.graphql",
+		"--no-publish",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --spec must be an OpenAPI spec"
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
 func TestSignetCanGetLatestSpec(t *testing.T) {
 	realGetNpmPkgRoot := getNpmPkgRoot
 	realosWriteFile := osWriteFile
@@ -81,11 +136,14 @@ func TestSignetCanGetLatestSpec(t *testing.T) {
 	server, req := mockServerForGetSpecsReq200OK(t)
 	defer server.Close()
 
+	workDir := t.TempDir()
+
 	flags := []string{
 		"--version=version1",
 		"--name", "user_service",
 		"--broker-url", server.URL,
 		"--provider-url", "http://localhost:3002",
+		"--work-dir", workDir,
 	}
 	actual := callSignetTest(flags)
 
@@ -96,8 +154,8 @@ func TestSignetCanGetLatestSpec(t *testing.T) {
 	})
 
 	t.Run("calls os.WriteFile with correct arguments", func(t *testing.T) {
-		if specPath != "/testDir/specs/spec.json" {
-			t.Error()
+		if filepath.Dir(filepath.Dir(specPath)) != workDir || filepath.Base(specPath) != "spec.json" {
+			t.Error(specPath)
 		}
 
 		if len(spec) == 0 {
@@ -110,9 +168,276 @@ func TestSignetCanGetLatestSpec(t *testing.T) {
 	})
 
 	t.Run("test stopped at the correct place", func(t *testing.T) {
-		expected := "Error: Failed to write specs/spec file: stop this test here"
+		expected := "Error: Failed to write spec file: stop this test here"
 		actual.startsWith(expected, t)
 	})
+
+	t.Run("removes the temporary directory", func(t *testing.T) {
+		entries, _ := os.ReadDir(workDir)
+		if len(entries) != 0 {
+			t.Error(entries)
+		}
+	})
+
+	teardown()
+}
+
+func TestSignetTestWritesStateSetupHooks(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realosWriteFile := osWriteFile
+	defer func() {
+		getNpmPkgRoot = realGetNpmPkgRoot
+		osWriteFile = realosWriteFile
+	}()
+
+	getNpmPkgRoot = func() (string, error) { return "/testDir", nil }
+
+	var hookfilePath string
+	var hookfile []byte
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		if strings.HasSuffix(name, "spec.json") {
+			return nil
+		}
+
+		hookfilePath, hookfile = name, data
+		return errors.New("stop this test here")
+	}
+
+	server, _ := mockServerForGetSpecsReq200OK(t)
+	defer server.Close()
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url", server.URL,
+		"--provider-url", "http://localhost:3002",
+		"--state-setup-url", "http://localhost:3002/_state",
+		"--before-hook", "./seed.sh 'users'",
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("writes the hookfile next to the spec", func(t *testing.T) {
+		if !strings.HasPrefix(filepath.Base(filepath.Dir(hookfilePath)), "signet-test-") || filepath.Base(hookfilePath) != "hooks.js" {
+			t.Error(hookfilePath)
+		}
+	})
+
+	t.Run("hookfile has the state setup url and hooks", func(t *testing.T) {
+		script := string(hookfile)
+
+		if !strings.Contains(script, `const stateSetupURL = "http://localhost:3002/_state";`) {
+			t.Error(script)
+		}
+
+		if !strings.Contains(script, `const beforeHook = "./seed.sh 'users'";`) || !strings.Contains(script, `const afterHook = "";`) {
+			t.Error(script)
+		}
+	})
+
+	t.Run("test stopped at the correct place", func(t *testing.T) {
+		expected := "Error: Failed to write hooks file: stop this test here"
+		actual.startsWith(expected, t)
+	})
+
+	teardown()
+}
+
+func TestSignetTestWritesRequestHooks(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realosWriteFile := osWriteFile
+	defer func() {
+		getNpmPkgRoot = realGetNpmPkgRoot
+		osWriteFile = realosWriteFile
+	}()
+
+	getNpmPkgRoot = func() (string, error) { return "/testDir", nil }
+
+	var hookfile []byte
+	osWriteFile = func(name string, data []byte, perm fs.FileMode) error {
+		if strings.HasSuffix(name, "spec.json") {
+			return nil
+		}
+
+		hookfile = data
+		return errors.New("stop this test here")
+	}
+
+	server, _ := mockServerForGetSpecsReq200OK(t)
+	defer server.Close()
+
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url", server.URL,
+		"--provider-url", "http://localhost:3002",
+		"-H", "Authorization=Bearer token",
+		"--request-filter", "./scripts/sign-request.sh",
+	}
+	callSignetTest(flags)
+	script := string(hookfile)
+
+	t.Run("hookfile has the request headers", func(t *testing.T) {
+		if !strings.Contains(script, `const requestHeaders = {"Authorization":"Bearer token"};`) {
+			t.Error(script)
+		}
+	})
+
+	t.Run("hookfile has the request filter", func(t *testing.T) {
+		if !strings.Contains(script, `const requestFilter = "./scripts/sign-request.sh";`) {
+			t.Error(script)
+		}
+	})
+
+	t.Run("hookfile has no state setup url", func(t *testing.T) {
+		if !strings.Contains(script, `const stateSetupURL = "";`) {
+			t.Error(script)
+		}
+	})
+
+	teardown()
+}
+
+func TestSignetTestUnsupportedReportFormat(t *testing.T) {
+	flags := []string{
+		"--version=version1",
+		"--name", "user_service",
+		"--broker-url=http://localhost:3000",
+		"--provider-url", "http://localhost:3002",
+		"--report", "html=./results.html",
+	}
+	actual := callSignetTest(flags)
+	expected := "Error: --report format html is not supported."
+
+	actual.startsWith(expected, t)
+	teardown()
+}
+
+func TestWriteVerificationReports(t *testing.T) {
+	junitPath := filepath.Join(t.TempDir(), "results.xml")
+	jsonPath := filepath.Join(t.TempDir(), "results.json")
+	reports := map[string]string{"junit": junitPath, "json": jsonPath}
+
+	results, err := utils.LoadVerificationResults("../data_test/verification-results.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeVerificationReports(results, reports, "user_service", "version1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("junit report has a test case per operation/response", func(t *testing.T) {
+		junitBytes, err := os.ReadFile(junitPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		junit := string(junitBytes)
+
+		if !strings.Contains(junit, `<testsuite name="user_service" tests="3" failures="1" skipped="1" time="0.042">`) {
+			t.Error(junit)
+		}
+
+		if !strings.Contains(junit, `<testcase classname="user_service.POST /users" name="/users &gt; POST &gt; 201 &gt; application/json" time="0.030">`) {
+			t.Error(junit)
+		}
+	})
+
+	t.Run("junit failures have expected and actual responses", func(t *testing.T) {
+		junitBytes, _ := os.ReadFile(junitPath)
+		junit := string(junitBytes)
+
+		if !strings.Contains(junit, "Expected: 201") || !strings.Contains(junit, "Actual: 500") {
+			t.Error(junit)
+		}
+	})
+
+	t.Run("json report has totals and results", func(t *testing.T) {
+		jsonBytes, err := os.ReadFile(jsonPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var report utils.VerificationReport
+		json.Unmarshal(jsonBytes, &report)
+
+		if report.Passed != 1 || report.Failed != 1 || report.Skipped != 1 || len(report.Results) != 3 {
+			t.Error(report)
+		}
+
+		if report.Provider != "user_service" || report.Version != "version1" {
+			t.Error(report)
+		}
+	})
+}
+
+func TestSignetTestFailedVerificationNotPublished(t *testing.T) {
+	realGetNpmPkgRoot := getNpmPkgRoot
+	realRunDredd := runDredd
+	defer func() {
+		getNpmPkgRoot = realGetNpmPkgRoot
+		runDredd = realRunDredd
+	}()
+
+	signetRoot := t.TempDir()
+	getNpmPkgRoot = func() (string, error) { return signetRoot, nil }
+
+	var hookfilePath string
+	runDredd = func(dreddPath, specPath, providerURL, hookfile string) (string, error) {
+		hookfilePath = hookfile
+		results, _ := os.ReadFile("../data_test/verification-results.json")
+		os.WriteFile(filepath.Join(filepath.Dir(hookfile), "results.json"), results, 0644)
+		return "fail: POST (201) /users duration: 30ms\n", errors.New("exit status 1")
+	}
+
+	brokerRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokerRequests++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	flags := []string{
+		"--version=version1",
+		"--branch=main",
+		"--name", "user_service",
+		"--broker-url", server.URL,
+		"--provider-url", "http://localhost:3002",
+		"--spec", "../data_test/api-spec.json",
+	}
+	actual := callSignetTest(flags)
+
+	t.Run("results are recorded by the hooks", func(t *testing.T) {
+		if !strings.HasPrefix(filepath.Base(filepath.Dir(hookfilePath)), "signet-test-") || filepath.Base(hookfilePath) != "hooks.js" {
+			t.Error(hookfilePath)
+		}
+	})
+
+	t.Run("spec is not published to the broker", func(t *testing.T) {
+		if brokerRequests != 0 {
+			t.Errorf("expected no requests to the broker, got %d", brokerRequests)
+		}
+		actual.contains("The spec was not published to the Signet broker, so this version remains unverified", t)
+	})
+
+	t.Run("prints a summary of failing operations", func(t *testing.T) {
+		actual.contains("Failing operations:\n  /users > POST > 201 > application/json: statusCode: Expected status code '201', but got '500'.\n", t)
+	})
+
+	t.Run("returns an error", func(t *testing.T) {
+		actual.contains("Error: provider verification failed with 1 failing operations", t)
+	})
+
+	teardown()
+}
+
+func TestFailingOperationsFromOutput(t *testing.T) {
+	output := "fail: GET (200) /users/1 duration: 12ms\nfail: body: Real and expected data does not match.\npass: POST (201) /users duration: 8ms\n"
+	failing := utils.FailingOperationsFromOutput(output)
+
+	if len(failing) != 2 || failing[0] != "GET (200) /users/1" {
+		t.Error(failing)
+	}
 }
 
 func TestPublishProviderUtilWithoutVersion(t *testing.T) {
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var workDir string
+var keepArtifacts bool
+
+/*
+createWorkDir makes a new directory for the scratch files of one invocation
+(ex. the spec and hooks for dredd, or the mountebank config and data), so that
+concurrent runs on the same machine do not overwrite each other's files. The
+directory is made inside --work-dir, or the system temp directory by default.
+The returned cleanup removes it, unless --keep-artifacts is set.
+*/
+func createWorkDir(baseDir, prefix string) (string, func(), error) {
+	if len(baseDir) != 0 {
+		err := os.MkdirAll(baseDir, 0755)
+		if err != nil {
+			return "", nil, errors.New("Failed to create --work-dir: " + err.Error())
+		}
+	}
+
+	dir, err := os.MkdirTemp(baseDir, prefix)
+	if err != nil {
+		return "", nil, errors.New("Failed to create work directory: " + err.Error())
+	}
+
+	cleanup := func() {
+		if keepArtifacts {
+			fmt.Println("Kept artifacts in " + dir)
+			return
+		}
+		os.RemoveAll(dir)
+	}
+
+	return dir, cleanup, nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateWorkDir(t *testing.T) {
+	baseDir := filepath.Join(t.TempDir(), "work")
+
+	first, cleanupFirst, err := createWorkDir(baseDir, "signet-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, cleanupSecond, err := createWorkDir(baseDir, "signet-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("each invocation gets its own directory in --work-dir", func(t *testing.T) {
+		if first == second || filepath.Dir(first) != baseDir || filepath.Dir(second) != baseDir {
+			t.Error(first, second)
+		}
+	})
+
+	t.Run("cleanup removes the directory", func(t *testing.T) {
+		cleanupFirst()
+		if _, err := os.Stat(first); !os.IsNotExist(err) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("--keep-artifacts keeps the directory", func(t *testing.T) {
+		keepArtifacts = true
+		cleanupSecond()
+		if _, err := os.Stat(second); err != nil {
+			t.Error(err)
+		}
+	})
+
+	teardown()
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Binary bodies are written to contracts as base64 strings. The request or
+response (or multipart part) carrying one is marked with BodyEncodingKey, so
+that tools replaying or comparing the contract know to decode the body first.
+*/
+const BodyEncodingKey = "bodyEncoding"
+const Base64Encoding = "base64"
+
+/*
+DecodeBody converts a recorded body to the form it is written to a contract
+in, based on its content type:
+
+  - JSON bodies are parsed into objects
+  - form encoded bodies become a map of field names to values
+  - multipart bodies become a list of parts, each with its own content type
+  - binary bodies are base64 encoded
+
+encoding is "base64" when the recorded body is already base64 encoded. The
+returned encoding is "base64" when the returned body is base64 encoded.
+*/
+func DecodeBody(body interface{}, contentType string, encoding string) (interface{}, string) {
+	str, ok := body.(string)
+	if !ok || len(str) == 0 {
+		return body, ""
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+
+	if encoding == Base64Encoding {
+		if !isTextMediaType(mediaType) {
+			return str, Base64Encoding
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(str)
+		if err != nil || !utf8.Valid(decoded) {
+			return str, Base64Encoding
+		}
+		str = string(decoded)
+	}
+
+	return decodeBodyBytes([]byte(str), mediaType, params)
+}
+
+func decodeBodyBytes(bodyBytes []byte, mediaType string, params map[string]string) (interface{}, string) {
+	switch {
+	case isJSONMediaType(mediaType):
+		var parsed interface{}
+		if json.Unmarshal(bodyBytes, &parsed) == nil {
+			return parsed, ""
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(bodyBytes))
+		if err == nil {
+			return valuesMap(values), ""
+		}
+	case mediaType == "multipart/form-data" && len(params["boundary"]) != 0:
+		parts, err := decodeMultipart(bodyBytes, params["boundary"])
+		if err == nil {
+			return parts, ""
+		}
+	}
+
+	if !utf8.Valid(bodyBytes) || (len(mediaType) != 0 && !isTextMediaType(mediaType)) {
+		return base64.StdEncoding.EncodeToString(bodyBytes), Base64Encoding
+	}
+
+	return string(bodyBytes), ""
+}
+
+func decodeMultipart(bodyBytes []byte, boundary string) ([]map[string]interface{}, error) {
+	reader := multipart.NewReader(bytes.NewReader(bodyBytes), boundary)
+	parts := []map[string]interface{}{}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partBytes, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		partContentType := part.Header.Get("Content-Type")
+		if len(partContentType) == 0 {
+			partContentType = "text/plain"
+		}
+
+		mediaType, params, err := mime.ParseMediaType(partContentType)
+		if err != nil {
+			mediaType = ""
+		}
+
+		partBody, partEncoding := decodeBodyBytes(partBytes, mediaType, params)
+
+		decodedPart := map[string]interface{}{
+			"name":        part.FormName(),
+			"contentType": partContentType,
+			"body":        partBody,
+		}
+
+		if len(part.FileName()) != 0 {
+			decodedPart["filename"] = part.FileName()
+		}
+
+		if len(partEncoding) != 0 {
+			decodedPart[BodyEncodingKey] = partEncoding
+		}
+
+		parts = append(parts, decodedPart)
+	}
+}
+
+// single values are kept as strings, repeated fields as a list
+func valuesMap(values url.Values) map[string]interface{} {
+	valuesByName := map[string]interface{}{}
+	for key, vals := range values {
+		if len(vals) == 1 {
+			valuesByName[key] = vals[0]
+		} else {
+			valuesByName[key] = vals
+		}
+	}
+	return valuesByName
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isTextMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || isJSONMediaType(mediaType) || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+
+	switch mediaType {
+	case "", "application/xml", "application/javascript", "application/graphql",
+		"application/x-www-form-urlencoded", "multipart/form-data", "application/yaml", "application/x-yaml":
+		return true
+	}
+
+	return false
+}
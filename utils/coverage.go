@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoverageEntry is one response documented in the spec
+type CoverageEntry struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	Covered bool   `json:"covered"`
+}
+
+func (e CoverageEntry) String() string {
+	return strings.ToUpper(e.Method) + " " + e.Path + " " + e.Status
+}
+
+// CoverageReport lists the responses in a spec, and how many were verified
+type CoverageReport struct {
+	Covered int             `json:"covered"`
+	Total   int             `json:"total"`
+	Percent float64         `json:"percent"`
+	Entries []CoverageEntry `json:"entries"`
+}
+
+/*
+SpecCoverage lists each path, method, and response status documented in the
+spec, and whether a verification result exercised it. Skipped results do
+not count. Status ranges (ex. 4XX) are covered by any status in the range,
+and default responses by any status the operation does not document.
+*/
+func SpecCoverage(spec map[string]interface{}, results []VerificationResult) CoverageReport {
+	exercised := map[string][]int{}
+	for _, result := range results {
+		if result.Status == "skip" || result.Expected == nil {
+			continue
+		}
+		method, path, _ := strings.Cut(result.Operation, " ")
+		operation := strings.ToUpper(method) + " " + path
+		exercised[operation] = append(exercised[operation], result.Expected.StatusCode)
+	}
+
+	report := CoverageReport{Entries: []CoverageEntry{}}
+
+	for _, op := range SpecOperations(spec) {
+		responses, _ := op.Definition["responses"].(map[string]interface{})
+		statuses := []string{}
+		for status := range responses {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+
+		for _, status := range statuses {
+			entry := CoverageEntry{Method: strings.ToUpper(op.Method), Path: op.Path, Status: status}
+
+			for _, exercisedStatus := range exercised[op.String()] {
+				if statusCovers(status, exercisedStatus, responses) {
+					entry.Covered = true
+					break
+				}
+			}
+
+			report.Entries = append(report.Entries, entry)
+			report.Total++
+			if entry.Covered {
+				report.Covered++
+			}
+		}
+	}
+
+	if report.Total != 0 {
+		report.Percent = float64(report.Covered) * 100 / float64(report.Total)
+	}
+
+	return report
+}
+
+func statusCovers(specStatus string, status int, responses map[string]interface{}) bool {
+	statusStr := strconv.Itoa(status)
+
+	switch {
+	case specStatus == statusStr:
+		return true
+	case len(specStatus) == 3 && strings.HasSuffix(strings.ToUpper(specStatus), "XX"):
+		_, documented := responses[statusStr]
+		return !documented && specStatus[0] == statusStr[0]
+	case specStatus == "default":
+		_, documented := responses[statusStr]
+		_, rangeDocumented := responses[statusStr[:1]+"XX"]
+		return !documented && !rangeDocumented
+	}
+
+	return false
+}
+
+func WriteCoverageReport(report CoverageReport, reportPath string) error {
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(reportPath, reportBytes, 0644)
+}
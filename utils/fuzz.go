@@ -0,0 +1,600 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FuzzStringLength is the length of the oversized strings sent in fields without a maxLength
+const FuzzStringLength = 65536
+
+// how deep example values are generated for nested (and recursive) schemas
+const maxExampleDepth = 5
+
+/*
+FuzzCase is a request generated from the request schemas of an operation,
+that the provider should handle without a server error.
+*/
+type FuzzCase struct {
+	Description string
+	Request     *ReportedMessage
+	// Invalid requests break the spec, and must be rejected with a documented
+	// 4xx response. Other requests (ex. oversized strings in fields without a
+	// maxLength) only need a documented response that is not a 5xx.
+	Invalid bool
+}
+
+/*
+FuzzVerifier sends fuzz cases to a running provider. Requests go through the
+same hooks, state setup, request headers, and request filter as contract
+verification.
+*/
+type FuzzVerifier struct {
+	ProviderURL string
+	Hooks       DreddHooks
+	Client      *http.Client
+}
+
+func (v FuzzVerifier) VerifySpec(spec map[string]interface{}) []VerificationResult {
+	replay := ContractVerifier{ProviderURL: v.ProviderURL, Hooks: v.Hooks, Client: v.Client}
+	results := []VerificationResult{}
+
+	for _, op := range SpecOperations(spec) {
+		responses, _ := op.Definition["responses"].(map[string]interface{})
+
+		for _, fuzzCase := range FuzzCases(spec, op) {
+			result := VerificationResult{
+				Name:      op.Path + " > " + strings.ToUpper(op.Method) + " > fuzz > " + fuzzCase.Description,
+				Operation: op.String(),
+				Request:   fuzzCase.Request,
+			}
+			results = append(results, replay.exchange(result, result.Name, fuzzCheck(responses, fuzzCase.Invalid)))
+		}
+	}
+
+	return results
+}
+
+func fuzzCheck(responses map[string]interface{}, invalid bool) func(int, http.Header, []byte) []string {
+	return func(status int, headers http.Header, body []byte) []string {
+		statusStr := strconv.Itoa(status)
+
+		switch {
+		case status >= 500:
+			return []string{"expected a documented 4xx response, but got " + statusStr}
+		case invalid && status < 400:
+			return []string{"expected the invalid request to be rejected with a documented 4xx response, but got " + statusStr}
+		case !documentedStatus(responses, status):
+			return []string{"status " + statusStr + " is not documented in the spec"}
+		}
+		return nil
+	}
+}
+
+func documentedStatus(responses map[string]interface{}, status int) bool {
+	for specStatus := range responses {
+		if statusCovers(specStatus, status, responses) {
+			return true
+		}
+	}
+	return false
+}
+
+type fuzzParameter struct {
+	name     string
+	in       string
+	required bool
+	schema   map[string]interface{}
+	value    interface{}
+	send     bool
+}
+
+// fuzzRequest is a valid request for an operation, which fuzz cases change one value of
+type fuzzRequest struct {
+	method  string
+	path    string
+	params  []fuzzParameter
+	body    interface{}
+	hasBody bool
+}
+
+/*
+FuzzCases generates boundary and invalid requests for an operation from its
+parameter and JSON request body schemas: missing required fields, values of
+the wrong type, values outside of minimum/maximum, minLength/maxLength, and
+enum, and oversized strings. Each case changes one value of a request built
+from the examples in the spec, or from values generated from the schemas.
+*/
+func FuzzCases(spec map[string]interface{}, op SpecOperation) []FuzzCase {
+	bodySchema, bodyExample, bodyRequired, hasBody, fuzzable := requestBodyContent(spec, op)
+	if !fuzzable {
+		return []FuzzCase{}
+	}
+
+	base := fuzzRequest{
+		method:  strings.ToUpper(op.Method),
+		path:    op.Path,
+		params:  operationParameters(spec, op),
+		body:    bodyExample,
+		hasBody: hasBody,
+	}
+
+	cases := []FuzzCase{}
+
+	for i, param := range base.params {
+		if param.in != "query" && param.in != "path" && param.in != "header" {
+			continue
+		}
+		label := param.in + " parameter " + param.name
+
+		if param.required && param.in != "path" {
+			omitted := base.withParam(i, nil)
+			omitted.params[i].send = false
+			cases = append(cases, FuzzCase{Description: label + ": missing required parameter", Request: omitted.build(), Invalid: true})
+		}
+
+		for _, value := range fuzzValues(spec, param.schema, false) {
+			cases = append(cases, FuzzCase{Description: label + ": " + value.description, Request: base.withParam(i, value.value).build(), Invalid: value.invalid})
+		}
+	}
+
+	if !hasBody {
+		return cases
+	}
+
+	if bodyRequired {
+		cases = append(cases, FuzzCase{Description: "body: missing required request body", Request: base.withBody(nil, false).build(), Invalid: true})
+	}
+
+	if wrongType, ok := wrongTypeValue(schemaType(bodySchema)); ok {
+		cases = append(cases, FuzzCase{Description: "body: " + describeWrongType(wrongType, schemaType(bodySchema)), Request: base.withBody(wrongType, true).build(), Invalid: true})
+	}
+
+	bodyObject, ok := bodyExample.(map[string]interface{})
+	if !ok {
+		return cases
+	}
+
+	properties, required := objectProperties(spec, bodySchema)
+	for _, name := range required {
+		cases = append(cases, FuzzCase{Description: "body field " + name + ": missing required field", Request: base.withBody(withoutField(bodyObject, name), true).build(), Invalid: true})
+	}
+
+	for _, name := range sortedKeys(properties) {
+		propertySchema, _ := properties[name].(map[string]interface{})
+		for _, value := range fuzzValues(spec, propertySchema, true) {
+			cases = append(cases, FuzzCase{Description: "body field " + name + ": " + value.description, Request: base.withBody(withField(bodyObject, name, value.value), true).build(), Invalid: value.invalid})
+		}
+	}
+
+	return cases
+}
+
+func (r fuzzRequest) withParam(index int, value interface{}) fuzzRequest {
+	params := make([]fuzzParameter, len(r.params))
+	copy(params, r.params)
+	params[index].value = value
+	params[index].send = true
+	r.params = params
+	return r
+}
+
+func (r fuzzRequest) withBody(body interface{}, hasBody bool) fuzzRequest {
+	r.body = body
+	r.hasBody = hasBody
+	return r
+}
+
+func (r fuzzRequest) build() *ReportedMessage {
+	request := &ReportedMessage{Method: r.method, URI: r.path, Headers: map[string]interface{}{}}
+	query := url.Values{}
+
+	for _, param := range r.params {
+		if !param.send {
+			continue
+		}
+
+		value := parameterString(param.value)
+		switch param.in {
+		case "path":
+			request.URI = strings.ReplaceAll(request.URI, "{"+param.name+"}", url.PathEscape(value))
+		case "query":
+			query.Add(param.name, value)
+		case "header":
+			request.Headers[param.name] = value
+		}
+	}
+
+	if len(query) != 0 {
+		request.URI += "?" + query.Encode()
+	}
+
+	if r.hasBody {
+		bodyBytes, _ := json.Marshal(r.body)
+		request.Body = string(bodyBytes)
+		request.Headers["Content-Type"] = "application/json"
+	}
+
+	return request
+}
+
+func parameterString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// path level parameters are overridden by operation parameters with the same name and location
+func operationParameters(spec map[string]interface{}, op SpecOperation) []fuzzParameter {
+	paths, _ := spec["paths"].(map[string]interface{})
+	pathItem, _ := paths[op.Path].(map[string]interface{})
+
+	params := []fuzzParameter{}
+	indexes := map[string]int{}
+
+	for _, list := range []interface{}{pathItem["parameters"], op.Definition["parameters"]} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			definition := resolveSpecRef(spec, item)
+			param := fuzzParameter{
+				name:     fmt.Sprint(definition["name"]),
+				in:       fmt.Sprint(definition["in"]),
+				required: definition["required"] == true,
+				schema:   resolveSpecRef(spec, definition["schema"]),
+			}
+			if param.in == "body" || param.in == "formData" {
+				continue
+			}
+
+			// Swagger 2.0 parameters have their schema inline
+			if _, hasSchema := definition["schema"]; !hasSchema {
+				param.schema = definition
+			}
+
+			value, hasExample := definition["example"]
+			if !hasExample {
+				value, hasExample = schemaExample(param.schema)
+			}
+			if !hasExample {
+				value = exampleValue(spec, param.schema, 0)
+			}
+			param.value = value
+			param.send = param.required || hasExample
+
+			key := param.in + " " + param.name
+			if i, ok := indexes[key]; ok {
+				params[i] = param
+			} else {
+				indexes[key] = len(params)
+				params = append(params, param)
+			}
+		}
+	}
+
+	return params
+}
+
+/*
+requestBodyContent finds the JSON request body of an operation. Operations
+with a request body that is not JSON are not fuzzed, because a valid
+request cannot be built for them.
+*/
+func requestBodyContent(spec map[string]interface{}, op SpecOperation) (schema map[string]interface{}, example interface{}, required, hasBody, fuzzable bool) {
+	// Swagger 2.0 request bodies are body parameters
+	if params, ok := op.Definition["parameters"].([]interface{}); ok {
+		for _, item := range params {
+			param := resolveSpecRef(spec, item)
+			if param["in"] == "body" {
+				schema = resolveSpecRef(spec, param["schema"])
+				return schema, exampleValue(spec, schema, 0), param["required"] == true, true, true
+			}
+		}
+	}
+
+	requestBody := resolveSpecRef(spec, op.Definition["requestBody"])
+	if len(requestBody) == 0 {
+		return nil, nil, false, false, true
+	}
+
+	content, _ := requestBody["content"].(map[string]interface{})
+	for _, mediaType := range sortedKeys(content) {
+		if !strings.Contains(strings.ToLower(mediaType), "json") {
+			continue
+		}
+
+		media, _ := content[mediaType].(map[string]interface{})
+		schema = resolveSpecRef(spec, media["schema"])
+
+		example, hasExample := media["example"]
+		if !hasExample {
+			example, hasExample = firstExample(media["examples"])
+		}
+		if !hasExample {
+			example = exampleValue(spec, schema, 0)
+		}
+
+		return schema, example, requestBody["required"] == true, true, true
+	}
+
+	return nil, nil, false, true, false
+}
+
+// examples is a map of names to example objects, the first by name is used
+func firstExample(examples interface{}) (interface{}, bool) {
+	examplesMap, _ := examples.(map[string]interface{})
+	for _, name := range sortedKeys(examplesMap) {
+		example, _ := examplesMap[name].(map[string]interface{})
+		if value, ok := example["value"]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// resolveSpecRef follows local $refs (ex. #/components/schemas/User) to the object they point to
+func resolveSpecRef(spec map[string]interface{}, value interface{}) map[string]interface{} {
+	object, _ := value.(map[string]interface{})
+
+	for i := 0; i < 10 && object != nil; i++ {
+		ref, ok := object["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			break
+		}
+
+		var target interface{} = spec
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+			targetMap, _ := target.(map[string]interface{})
+			target = targetMap[key]
+		}
+		object, _ = target.(map[string]interface{})
+	}
+
+	return object
+}
+
+func schemaType(schema map[string]interface{}) string {
+	if schemaType, ok := schema["type"].(string); ok {
+		return schemaType
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// objectProperties merges the properties and required fields of a schema and its allOf schemas
+func objectProperties(spec map[string]interface{}, schema map[string]interface{}) (map[string]interface{}, []string) {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	if schemaProperties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range schemaProperties {
+			properties[name] = resolveSpecRef(spec, property)
+		}
+	}
+	if schemaRequired, ok := schema["required"].([]interface{}); ok {
+		for _, name := range schemaRequired {
+			required = append(required, fmt.Sprint(name))
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, member := range allOf {
+			memberProperties, memberRequired := objectProperties(spec, resolveSpecRef(spec, member))
+			for name, property := range memberProperties {
+				properties[name] = property
+			}
+			required = append(required, memberRequired...)
+		}
+	}
+
+	sort.Strings(required)
+	return properties, required
+}
+
+func schemaExample(schema map[string]interface{}) (interface{}, bool) {
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return value, true
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) != 0 {
+		return enum[0], true
+	}
+	return nil, false
+}
+
+// exampleValue generates a value that is valid for the schema
+func exampleValue(spec map[string]interface{}, schema map[string]interface{}, depth int) interface{} {
+	if value, ok := schemaExample(schema); ok {
+		return value
+	}
+
+	switch schemaType(schema) {
+	case "string":
+		return exampleString(schema)
+	case "integer", "number":
+		value := 1.0
+		if minimum, ok := schemaNumber(schema, "minimum"); ok {
+			value = minimum
+			if schema["exclusiveMinimum"] == true {
+				value++
+			}
+		} else if maximum, ok := schemaNumber(schema, "maximum"); ok && maximum < value {
+			value = maximum
+			if schema["exclusiveMaximum"] == true {
+				value--
+			}
+		}
+		return value
+	case "boolean":
+		return true
+	case "array":
+		items := []interface{}{}
+		if depth < maxExampleDepth {
+			items = append(items, exampleValue(spec, resolveSpecRef(spec, schema["items"]), depth+1))
+		}
+		return items
+	case "object":
+		object := map[string]interface{}{}
+		properties, required := objectProperties(spec, schema)
+
+		// past the maximum depth, only required properties are generated
+		names := required
+		if depth < maxExampleDepth {
+			names = sortedKeys(properties)
+		}
+		for _, name := range names {
+			propertySchema, _ := properties[name].(map[string]interface{})
+			object[name] = exampleValue(spec, propertySchema, depth+1)
+		}
+		return object
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) != 0 {
+		return exampleValue(spec, map[string]interface{}{"type": "object", "allOf": allOf}, depth)
+	}
+
+	return "signet"
+}
+
+func exampleString(schema map[string]interface{}) string {
+	switch schema["format"] {
+	case "date":
+		return "2024-01-01"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "email":
+		return "signet@example.com"
+	case "uuid":
+		return "00000000-0000-4000-8000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	value := "signet"
+	if minLength, ok := schemaNumber(schema, "minLength"); ok && len(value) < int(minLength) {
+		value += strings.Repeat("x", int(minLength)-len(value))
+	}
+	if maxLength, ok := schemaNumber(schema, "maxLength"); ok && len(value) > int(maxLength) {
+		value = value[:int(maxLength)]
+	}
+	return value
+}
+
+// JSON specs have float64 numbers, YAML specs have ints
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	switch value := schema[key].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	}
+	return 0, false
+}
+
+type fuzzValue struct {
+	description string
+	value       interface{}
+	invalid     bool
+}
+
+/*
+fuzzValues lists the values to try for a parameter or body field. Wrong
+types are not tried for string parameters, because every parameter is sent
+as a string, and oversized strings are only sent in bodies.
+*/
+func fuzzValues(spec map[string]interface{}, schema map[string]interface{}, inBody bool) []fuzzValue {
+	values := []fuzzValue{}
+	valueType := schemaType(schema)
+
+	if wrongType, ok := wrongTypeValue(valueType); ok && (inBody || valueType != "string") {
+		values = append(values, fuzzValue{describeWrongType(wrongType, valueType), wrongType, true})
+	}
+
+	if valueType == "integer" {
+		values = append(values, fuzzValue{"not an integer (1.5)", 1.5, true})
+	}
+
+	if minimum, ok := schemaNumber(schema, "minimum"); ok {
+		below := minimum - 1
+		if schema["exclusiveMinimum"] == true {
+			below = minimum
+		}
+		values = append(values, fuzzValue{"below minimum (" + parameterString(below) + ")", below, true})
+	}
+
+	if maximum, ok := schemaNumber(schema, "maximum"); ok {
+		above := maximum + 1
+		if schema["exclusiveMaximum"] == true {
+			above = maximum
+		}
+		values = append(values, fuzzValue{"above maximum (" + parameterString(above) + ")", above, true})
+	}
+
+	if valueType != "string" {
+		return values
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) != 0 {
+		values = append(values, fuzzValue{"not in enum", "signet-fuzz-not-in-enum", true})
+	}
+
+	if minLength, ok := schemaNumber(schema, "minLength"); ok && minLength > 0 {
+		values = append(values, fuzzValue{"shorter than minLength (" + parameterString(minLength-1) + " characters)", strings.Repeat("x", int(minLength)-1), true})
+	}
+
+	if maxLength, ok := schemaNumber(schema, "maxLength"); ok {
+		values = append(values, fuzzValue{"longer than maxLength (" + parameterString(maxLength+1) + " characters)", strings.Repeat("x", int(maxLength)+1), true})
+	} else if inBody && schema["enum"] == nil && schema["format"] == nil {
+		values = append(values, fuzzValue{"oversized string (" + strconv.Itoa(FuzzStringLength) + " characters)", strings.Repeat("x", FuzzStringLength), false})
+	}
+
+	return values
+}
+
+func wrongTypeValue(valueType string) (interface{}, bool) {
+	switch valueType {
+	case "string":
+		return 12345, true
+	case "integer", "number", "boolean", "array", "object":
+		return "signet-fuzz", true
+	}
+	return nil, false
+}
+
+func describeWrongType(value interface{}, valueType string) string {
+	if _, ok := value.(string); ok {
+		return "wrong type (string instead of " + valueType + ")"
+	}
+	return "wrong type (number instead of " + valueType + ")"
+}
+
+func withField(object map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	copied := withoutField(object, name)
+	copied[name] = value
+	return copied
+}
+
+func withoutField(object map[string]interface{}, name string) map[string]interface{} {
+	copied := map[string]interface{}{}
+	for key, value := range object {
+		if key != name {
+			copied[key] = value
+		}
+	}
+	return copied
+}
@@ -0,0 +1,447 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+/*
+GraphQLOperation is a GraphQL request recorded by signet proxy. Every call to
+a GraphQL API goes to the same path, so the operation is what tells recorded
+interactions apart.
+*/
+type GraphQLOperation struct {
+	Type          string
+	Name          string
+	Query         string
+	OperationName string
+	Variables     interface{}
+	RootFields    []string
+}
+
+func (op GraphQLOperation) Description() string {
+	if len(op.Name) != 0 {
+		return op.Type + " " + op.Name
+	}
+	return op.Type + " { " + strings.Join(op.RootFields, " ") + " }"
+}
+
+// the request body as it is written to the contract, with the query normalized
+func (op GraphQLOperation) Body() map[string]interface{} {
+	body := map[string]interface{}{"query": op.Query}
+	if len(op.OperationName) != 0 {
+		body["operationName"] = op.OperationName
+	}
+	if op.Variables != nil {
+		body["variables"] = op.Variables
+	}
+	return body
+}
+
+/*
+ParseGraphQLRequest detects GraphQL requests recorded by mountebank. A request
+is GraphQL when it has an application/graphql body, or when it is sent to a
+path ending in /graphql with a query in its JSON body or query string.
+*/
+func ParseGraphQLRequest(method, requestPath, contentType string, body interface{}, query map[string]interface{}) (GraphQLOperation, bool) {
+	var payload map[string]interface{}
+
+	if strings.HasPrefix(contentType, "application/graphql") {
+		queryStr, ok := body.(string)
+		if !ok {
+			return GraphQLOperation{}, false
+		}
+		payload = map[string]interface{}{"query": queryStr}
+	} else if strings.HasSuffix(strings.TrimSuffix(requestPath, "/"), "/graphql") {
+		switch typedBody := body.(type) {
+		case map[string]interface{}:
+			payload = typedBody
+		case string:
+			json.Unmarshal([]byte(typedBody), &payload)
+		}
+
+		if payload == nil && method == "GET" {
+			payload = map[string]interface{}{}
+			for key, value := range query {
+				payload[key] = value
+			}
+			if variables, ok := payload["variables"].(string); ok {
+				var decoded interface{}
+				if json.Unmarshal([]byte(variables), &decoded) == nil {
+					payload["variables"] = decoded
+				}
+			}
+		}
+	}
+
+	queryStr, ok := payload["query"].(string)
+	if !ok || len(strings.TrimSpace(queryStr)) == 0 {
+		return GraphQLOperation{}, false
+	}
+
+	operationName, _ := payload["operationName"].(string)
+	op, ok := parseGraphQLOperation(queryStr, operationName)
+	if !ok {
+		return GraphQLOperation{}, false
+	}
+	op.OperationName = operationName
+	op.Variables = payload["variables"]
+
+	return op, true
+}
+
+func parseGraphQLOperation(query, operationName string) (GraphQLOperation, bool) {
+	tokens := tokenizeGraphQL(query)
+
+	for i := 0; i < len(tokens); {
+		end := skipGraphQLDefinition(tokens, i)
+
+		if tokens[i] == "fragment" {
+			i = end
+			continue
+		}
+
+		op := GraphQLOperation{Type: "query"}
+		if tokens[i] != "{" {
+			switch tokens[i] {
+			case "query", "mutation", "subscription":
+				op.Type = tokens[i]
+			default:
+				return GraphQLOperation{}, false
+			}
+
+			if i+1 < len(tokens) && isGraphQLName(tokens[i+1]) {
+				op.Name = tokens[i+1]
+			}
+		}
+
+		if len(operationName) == 0 || op.Name == operationName {
+			op.RootFields = graphQLRootFields(tokens[i:end])
+			op.Query = joinGraphQLTokens(tokens)
+			return op, true
+		}
+
+		i = end
+	}
+
+	return GraphQLOperation{}, false
+}
+
+// index of the token after the definition starting at i
+func skipGraphQLDefinition(tokens []string, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// names of the fields selected at the top level of an operation
+func graphQLRootFields(tokens []string) []string {
+	fields := []string{}
+	braceDepth, parenDepth := 0, 0
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "{":
+			braceDepth++
+			continue
+		case "}":
+			braceDepth--
+			continue
+		case "(":
+			parenDepth++
+			continue
+		case ")":
+			parenDepth--
+			continue
+		}
+
+		if braceDepth != 1 || parenDepth != 0 || !isGraphQLName(tokens[i]) {
+			continue
+		}
+
+		prev := ""
+		if i > 0 {
+			prev = tokens[i-1]
+		}
+		if prev == "@" || prev == "..." || prev == "on" {
+			continue
+		}
+
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+		if next == ":" {
+			continue // alias, the field name follows the colon
+		}
+
+		fields = append(fields, tokens[i])
+	}
+
+	return fields
+}
+
+/*
+ValidateGraphQLContract checks that every GraphQL operation in a consumer
+contract only selects root fields that exist in the provider's SDL schema.
+It returns one message per problem found.
+*/
+func ValidateGraphQLContract(pactPath, sdl string) ([]string, error) {
+	contract, err := LoadContract(pactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := parseGraphQLSchema(sdl)
+	problems := []string{}
+
+	interactions, _ := contract.Interactions.([]interface{})
+	for _, item := range interactions {
+		interaction, _ := item.(map[string]interface{})
+		request, _ := interaction["request"].(map[string]interface{})
+		body, _ := request["body"].(map[string]interface{})
+
+		query, ok := body["query"].(string)
+		if !ok {
+			continue
+		}
+
+		operationName, _ := body["operationName"].(string)
+		op, ok := parseGraphQLOperation(query, operationName)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("interaction %q does not contain a valid GraphQL operation", interaction["description"]))
+			continue
+		}
+
+		rootType := schema.roots[op.Type]
+		fields, ok := schema.types[rootType]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: the provider schema does not define a %s root type", op.Description(), op.Type))
+			continue
+		}
+
+		for _, field := range op.RootFields {
+			if strings.HasPrefix(field, "__") {
+				continue
+			}
+			if !fields[field] {
+				problems = append(problems, fmt.Sprintf("%s: field %q does not exist on type %s", op.Description(), field, rootType))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+/*
+GraphQLSchemaFromSpec extracts SDL from a spec returned by the broker. GraphQL
+specs are published as plain text, which the broker may return as a JSON string.
+*/
+func GraphQLSchemaFromSpec(spec []byte) string {
+	var sdl string
+	if json.Unmarshal(spec, &sdl) == nil {
+		return sdl
+	}
+
+	var wrapped map[string]interface{}
+	if json.Unmarshal(spec, &wrapped) == nil {
+		if sdl, ok := wrapped["spec"].(string); ok {
+			return sdl
+		}
+	}
+
+	return string(spec)
+}
+
+type graphQLSchema struct {
+	roots map[string]string
+	types map[string]map[string]bool
+}
+
+func parseGraphQLSchema(sdl string) graphQLSchema {
+	schema := graphQLSchema{
+		roots: map[string]string{"query": "Query", "mutation": "Mutation", "subscription": "Subscription"},
+		types: map[string]map[string]bool{},
+	}
+
+	tokens := tokenizeGraphQL(sdl)
+	braceDepth, parenDepth := 0, 0
+	currentType := ""
+	inSchema := false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch token {
+		case "{":
+			braceDepth++
+			continue
+		case "}":
+			braceDepth--
+			if braceDepth == 0 {
+				currentType, inSchema = "", false
+			}
+			continue
+		case "(":
+			parenDepth++
+			continue
+		case ")":
+			parenDepth--
+			continue
+		}
+
+		if braceDepth == 0 {
+			if token == "schema" {
+				inSchema = true
+			}
+			if token == "type" && i+1 < len(tokens) {
+				currentType = tokens[i+1]
+				if schema.types[currentType] == nil {
+					schema.types[currentType] = map[string]bool{}
+				}
+			}
+			continue
+		}
+
+		if braceDepth != 1 || parenDepth != 0 || !isGraphQLName(token) || i+1 >= len(tokens) {
+			continue
+		}
+
+		if inSchema && tokens[i+1] == ":" && i+2 < len(tokens) {
+			schema.roots[token] = tokens[i+2]
+			continue
+		}
+
+		if len(currentType) != 0 && tokens[i-1] != "@" && (tokens[i+1] == ":" || tokens[i+1] == "(") {
+			schema.types[currentType][token] = true
+		}
+	}
+
+	return schema
+}
+
+/*
+tokenizeGraphQL splits a GraphQL document into names, punctuators and string
+literals. Whitespace, commas and comments are insignificant in GraphQL and are
+dropped.
+*/
+func tokenizeGraphQL(src string) []string {
+	tokens := []string{}
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ',' || r == '\uFEFF':
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '"':
+			start := i
+			if i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"' {
+				i += 3
+				for i < len(runes) && !(runes[i] == '"' && i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"') {
+					i++
+				}
+				i += 3
+			} else {
+				i++
+				for i < len(runes) && runes[i] != '"' && runes[i] != '\n' {
+					if runes[i] == '\\' {
+						i++
+					}
+					i++
+				}
+				i++
+			}
+			if i > len(runes) {
+				i = len(runes)
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case r == '.' && i+2 < len(runes) && runes[i+1] == '.' && runes[i+2] == '.':
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.ContainsRune("!$&()=:@[]{}|", r):
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			start := i
+			for i < len(runes) && isGraphQLWordRune(runes[i]) {
+				i++
+			}
+			if i == start {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		}
+	}
+
+	return tokens
+}
+
+func isGraphQLWordRune(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || r == '+' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func isGraphQLName(token string) bool {
+	if len(token) == 0 {
+		return false
+	}
+	first := token[0]
+	return first == '_' || (first >= 'a' && first <= 'z') || (first >= 'A' && first <= 'Z')
+}
+
+// joins tokens with a single space only where a value or field would otherwise run into the previous one
+func joinGraphQLTokens(tokens []string) string {
+	var builder strings.Builder
+	for i, token := range tokens {
+		if i > 0 && endsGraphQLValue(tokens[i-1]) && startsGraphQLValue(token) {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(token)
+	}
+	return builder.String()
+}
+
+func startsGraphQLValue(token string) bool {
+	return token != "..." && (strings.HasPrefix(token, `"`) || isGraphQLWordRune(rune(token[0])))
+}
+
+func endsGraphQLValue(token string) bool {
+	return startsGraphQLValue(token) || token == "]" || token == "}"
+}
+
+// mountebank records query strings as a map of strings
+func queryMap(query interface{}) map[string]interface{} {
+	switch typedQuery := query.(type) {
+	case map[string]interface{}:
+		return typedQuery
+	case string:
+		values, err := url.ParseQuery(typedQuery)
+		if err != nil {
+			return nil
+		}
+		parsed := map[string]interface{}{}
+		for key := range values {
+			parsed[key] = values.Get(key)
+		}
+		return parsed
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request  harRequest  `json:"request"`
+	Response harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status  int            `json:"status"`
+	Headers []harNameValue `json:"headers"`
+	Content harContent     `json:"content"`
+}
+
+type harContent struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+/*
+LoadHARExchanges reads the exchanges captured in a HAR file. When baseURL is
+set, only requests to URLs under it are kept, which drops the unrelated
+traffic (ex. static assets, analytics) that browser captures usually include.
+Entries without a response (status 0) are skipped.
+*/
+func LoadHARExchanges(harPath string, baseURL string) ([]Exchange, error) {
+	harBytes, err := os.ReadFile(harPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var har harFile
+	err = json.Unmarshal(harBytes, &har)
+	if err != nil {
+		return nil, errors.New("failed to parse HAR file " + harPath + ": " + err.Error())
+	}
+
+	exchanges := []Exchange{}
+
+	for _, entry := range har.Log.Entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+
+		if len(baseURL) != 0 && !strings.HasPrefix(entry.Request.URL, baseURL) {
+			continue
+		}
+
+		requestURL, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, errors.New("HAR entry has an invalid url " + entry.Request.URL + ": " + err.Error())
+		}
+
+		exchange := Exchange{
+			Method:          strings.ToUpper(entry.Request.Method),
+			Path:            requestURL.Path,
+			Query:           harQuery(entry.Request.QueryString),
+			RequestHeaders:  harHeaders(entry.Request.Headers),
+			RequestBody:     "",
+			Status:          entry.Response.Status,
+			ResponseHeaders: harHeaders(entry.Response.Headers),
+			ResponseBody:    entry.Response.Content.Text,
+		}
+
+		if entry.Response.Content.Encoding == Base64Encoding {
+			exchange.ResponseBodyEncoding = Base64Encoding
+		}
+		if len(headerValue(exchange.ResponseHeaders, "Content-Type")) == 0 && len(entry.Response.Content.MimeType) != 0 {
+			exchange.ResponseHeaders["Content-Type"] = entry.Response.Content.MimeType
+		}
+
+		if entry.Request.PostData != nil {
+			exchange.RequestBody = entry.Request.PostData.Text
+			if len(headerValue(exchange.RequestHeaders, "Content-Type")) == 0 && len(entry.Request.PostData.MimeType) != 0 {
+				exchange.RequestHeaders["Content-Type"] = entry.Request.PostData.MimeType
+			}
+		}
+
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges, nil
+}
+
+// HTTP/2 captures use lowercase pseudo-headers like :authority, which are not kept
+func harHeaders(nameValues []harNameValue) map[string]interface{} {
+	headers := map[string]interface{}{}
+	for _, header := range nameValues {
+		if strings.HasPrefix(header.Name, ":") {
+			continue
+		}
+		headers[header.Name] = header.Value
+	}
+	return headers
+}
+
+// repeated parameters are recorded as a list, like mountebank does
+func harQuery(nameValues []harNameValue) map[string]interface{} {
+	query := map[string]interface{}{}
+	for _, param := range nameValues {
+		switch existing := query[param.Name].(type) {
+		case nil:
+			query[param.Name] = param.Value
+		case string:
+			query[param.Name] = []string{existing, param.Value}
+		case []string:
+			query[param.Name] = append(existing, param.Value)
+		}
+	}
+	return query
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -38,13 +37,15 @@ func LoadContract(path string) (contract Pact, err error) {
 }
 
 func LoadSpec(path string) (spec interface{}, format string, err error) {
-	format = path[len(path)-4:]
-	if format != "json" && format != "yaml" && format != ".yml" {
-		return nil, "", errors.New("spec must be either JSON or YAML")
-	}
-
-	if format == ".yml" {
+	switch filepath.Ext(path) {
+	case ".json":
+		format = "json"
+	case ".yaml", ".yml":
 		format = "yaml"
+	case ".graphql", ".gql":
+		format = "graphql"
+	default:
+		return nil, "", errors.New("spec must be either JSON, YAML, or a GraphQL schema")
 	}
 
 	specBytes, err := os.ReadFile(path)
@@ -171,20 +172,9 @@ func PublishProvider(path string, brokerURL string, ProviderName, version, branc
 		return errors.New("must set --name if --type is \"provider\"")
 	}
 
-	if branch == "auto" || (branch == "" && version == "auto") {
-		var err error
-		branch, err = SetBranchToCurrentGit(branch)
-		if err != nil {
-			return err
-		}
-	}
-
-	if version == "auto" {
-		var err error
-		version, err = SetVersionToGitSha(version)
-		if err != nil {
-			return err
-		}
+	version, branch, err := resolveProviderVersion(version, branch)
+	if err != nil {
+		return err
 	}
 
 	spec, specFormat, err := LoadSpec(path)
@@ -205,6 +195,27 @@ func PublishProvider(path string, brokerURL string, ProviderName, version, branc
 	return nil
 }
 
+// "auto" defaults the version to the git SHA of HEAD, and the branch to the current git branch
+func resolveProviderVersion(version, branch string) (string, string, error) {
+	var err error
+
+	if branch == "auto" || (branch == "" && version == "auto") {
+		branch, err = SetBranchToCurrentGit(branch)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if version == "auto" {
+		version, err = SetVersionToGitSha(version)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return version, branch, nil
+}
+
 func SliceOutNodeWarnings(str string) string {
 	re := regexp.MustCompile(`(?s)\(node(.+)warning was created\)\n`)
 	return re.ReplaceAllString(str, "")
@@ -227,47 +238,14 @@ func GetNpmPkgRoot() (string, error) {
 }
 
 func CreatePact(stubsPath string, pactPath string, consumerName string, providerName string) (error, bool) {
+	route := ProxyRoute{ProviderName: providerName, Path: pactPath}
 
-	pact := CreateDefaultPact(pactPath, consumerName, providerName)
-	matchPaths, err := GetMatchPaths(stubsPath)
-
-	if err != nil {
-		return err, false
-	}
-
-	interactions, err := createInteractions(matchPaths)
-	pact["interactions"] = interactions
-
+	writtenPaths, _, err := CreatePacts(stubsPath, consumerName, []ProxyRoute{route})
 	if err != nil {
 		return err, false
 	}
 
-	if len(interactions) == 0 {
-		return nil, false
-	}
-
-	err = WritePact(pact, pactPath)
-
-	if err != nil {
-		return err, false
-	}
-
-	return nil, true
-}
-
-func GetMatchPaths(stubsPath string) ([]string, error) {
-	matchPaths := []string{}
-
-	filepath.WalkDir(stubsPath, func(path string, d fs.DirEntry, err error) error {
-		dir := filepath.Dir(path)
-		parent := filepath.Base(dir)
-
-		if parent == "matches" {
-			matchPaths = append(matchPaths, path)
-		}
-		return err
-	})
-	return matchPaths, nil
+	return nil, len(writtenPaths) != 0
 }
 
 func WritePact(pact map[string]interface{}, pactPath string) error {
@@ -286,70 +264,6 @@ func CreatePactDir(pactDir string) error {
 	return err
 }
 
-func createInteractions(matchPaths []string) ([]map[string]interface{}, error) {
-	interactions := []map[string]interface{}{}
-
-	for _, matchPath := range matchPaths {
-		matchBytes, err := os.ReadFile(matchPath)
-
-		if err != nil {
-			return []map[string]interface{}{}, err
-		}
-
-		match := map[string]interface{}{}
-		err = json.Unmarshal(matchBytes, &match)
-
-		if err != nil {
-			return []map[string]interface{}{}, err
-		}
-
-		interaction := map[string]interface{}{}
-
-		request := match["request"].(map[string]any)
-		response := match["response"].(map[string]any)
-
-		interaction["description"] = fmt.Sprintf("%s %s %.0f", request["method"], request["path"], response["statusCode"])
-
-		requestHeaders := map[string]interface{}{}
-
-		requestContentType := request["headers"].(map[string]any)["Content-Type"]
-		requestAccept := request["headers"].(map[string]any)["Accept"]
-
-		if requestContentType != nil {
-			requestHeaders["Content-Type"] = requestContentType
-		}
-
-		if requestAccept != nil {
-			requestHeaders["Accept"] = requestAccept
-		}
-
-		responseHeaders := map[string]interface{}{}
-
-		responseContentType := response["headers"].(map[string]any)["Content-Type"]
-
-		if responseContentType != nil {
-			responseHeaders["Content-Type"] = responseContentType
-		}
-
-		interaction["request"] = map[string]interface{}{
-			"method":  request["method"],
-			"path":    request["path"],
-			"body":    request["body"],
-			"query":   request["query"],
-			"headers": requestHeaders,
-		}
-
-		interaction["response"] = map[string]interface{}{
-			"status":  response["statusCode"],
-			"headers": responseHeaders,
-			"body":    response["body"],
-		}
-
-		interactions = append(interactions, interaction)
-	}
-	return interactions, nil
-}
-
 func CreateDefaultPact(pactPath string, consumerName string, providerName string) (contract map[string]interface{}) {
 	return map[string]interface{}{
 		"consumer": map[string]interface{}{
@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"text/template"
+)
+
+/*
+DreddHooks configures the hookfile that signet test passes to dredd. Each
+setting is optional, and the hooks are only generated when at least one of
+them is set.
+*/
+type DreddHooks struct {
+	// StateSetupURL is sent a POST before (action "setup") and after
+	// (action "teardown") each interaction
+	StateSetupURL string
+	// BeforeHook and AfterHook are shell commands run before and after each interaction
+	BeforeHook string
+	AfterHook  string
+	// RequestHeaders are added to every request, replacing generated headers of the same name
+	RequestHeaders map[string]string
+	// RequestFilter is a shell command that is given each request as JSON on
+	// stdin, and prints the request to send on stdout
+	RequestFilter string
+	// ResultsPath is where the result of each interaction is written, as a
+	// JSON list of VerificationResult
+	ResultsPath string
+}
+
+func (h DreddHooks) IsEmpty() bool {
+	return len(h.StateSetupURL) == 0 && len(h.BeforeHook) == 0 && len(h.AfterHook) == 0 &&
+		len(h.RequestHeaders) == 0 && len(h.RequestFilter) == 0 && len(h.ResultsPath) == 0
+}
+
+/*
+the values are written into the script as JSON, which is also valid
+javascript, so that quotes in a URL or command cannot break the hookfile
+*/
+var dreddHooksTemplate = template.Must(template.New("hooks").Parse(`const hooks = require('hooks');
+const { execSync } = require('child_process');
+
+const stateSetupURL = {{.StateSetupURL}};
+const beforeHook = {{.BeforeHook}};
+const afterHook = {{.AfterHook}};
+const requestHeaders = {{.RequestHeaders}};
+const requestFilter = {{.RequestFilter}};
+const resultsPath = {{.ResultsPath}};
+const results = [];
+
+function providerState(transaction) {
+  return {
+    state: transaction.name,
+    operation: transaction.request.method + ' ' + transaction.origin.resourceName,
+  };
+}
+
+function postState(transaction, action, done) {
+  const url = new URL(stateSetupURL);
+  const client = url.protocol === 'https:' ? require('https') : require('http');
+  const body = JSON.stringify({ ...providerState(transaction), action });
+
+  const req = client.request(url, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', 'Content-Length': Buffer.byteLength(body) },
+  }, (res) => {
+    res.resume();
+    res.on('end', () => {
+      if (res.statusCode >= 400) {
+        transaction.fail = 'provider state ' + action + ' failed with status ' + res.statusCode;
+      }
+      done();
+    });
+  });
+
+  req.on('error', (err) => {
+    transaction.fail = 'provider state ' + action + ' failed: ' + err.message;
+    done();
+  });
+
+  req.end(body);
+}
+
+function runHook(command, transaction, input) {
+  const state = providerState(transaction);
+
+  try {
+    return execSync(command, {
+      input,
+      stdio: 'pipe',
+      env: { ...process.env, SIGNET_STATE: state.state, SIGNET_OPERATION: state.operation },
+    }).toString();
+  } catch (err) {
+    transaction.fail = 'hook "' + command + '" failed: ' + String(err.stderr || err.message).trim();
+  }
+}
+
+// header names are case insensitive, so a header replaces any generated one with the same name
+function setHeader(headers, name, value) {
+  Object.keys(headers)
+    .filter((existing) => existing.toLowerCase() === name.toLowerCase())
+    .forEach((existing) => delete headers[existing]);
+
+  headers[name] = value;
+}
+
+function filterRequest(transaction) {
+  const request = {
+    method: transaction.request.method,
+    uri: transaction.request.uri,
+    headers: transaction.request.headers,
+    body: transaction.request.body,
+    protocol: transaction.protocol,
+    host: transaction.host,
+    port: transaction.port,
+  };
+
+  const output = runHook(requestFilter, transaction, JSON.stringify(request));
+  if (output === undefined) {
+    return;
+  }
+
+  let filtered;
+  try {
+    filtered = JSON.parse(output);
+  } catch (err) {
+    transaction.fail = 'request filter "' + requestFilter + '" did not print a JSON request: ' + err.message;
+    return;
+  }
+
+  // fullPath includes any base path of the provider url, which is kept
+  const basePath = transaction.fullPath.slice(0, transaction.fullPath.length - request.uri.length);
+
+  ['method', 'uri', 'headers', 'body'].forEach((key) => {
+    if (filtered[key] !== undefined) {
+      transaction.request[key] = filtered[key];
+    }
+  });
+
+  ['protocol', 'host', 'port'].forEach((key) => {
+    if (filtered[key] !== undefined) {
+      transaction[key] = filtered[key];
+    }
+  });
+
+  transaction.fullPath = basePath + transaction.request.uri;
+}
+
+hooks.beforeEach((transaction, done) => {
+  if (beforeHook) {
+    runHook(beforeHook, transaction);
+  }
+
+  Object.keys(requestHeaders).forEach((name) => {
+    setHeader(transaction.request.headers, name, requestHeaders[name]);
+  });
+
+  if (requestFilter && !transaction.fail) {
+    filterRequest(transaction);
+  }
+
+  // timing starts once the provider state is set up, right before the request is sent
+  const started = () => {
+    transaction.signetStartedAt = Date.now();
+    done();
+  };
+
+  if (stateSetupURL && !transaction.fail) {
+    postState(transaction, 'setup', started);
+  } else {
+    started();
+  }
+});
+
+function failures(transaction) {
+  const messages = [];
+  if (typeof transaction.fail === 'string') {
+    messages.push(transaction.fail);
+  }
+
+  const validation = transaction.results || {};
+  if (validation.fields) {
+    Object.keys(validation.fields).forEach((field) => {
+      (validation.fields[field].errors || []).forEach((err) => messages.push(field + ': ' + err.message));
+    });
+  } else {
+    // dredd versions before 12 report validation results per section
+    Object.keys(validation).forEach((section) => {
+      (validation[section].results || [])
+        .filter((result) => result.severity === 'error')
+        .forEach((result) => messages.push(section + ': ' + result.message));
+    });
+  }
+
+  if (messages.length === 0 && transaction.test && transaction.test.message) {
+    messages.push(transaction.test.message);
+  }
+
+  return messages;
+}
+
+function status(transaction) {
+  if (transaction.test && transaction.test.status) {
+    return transaction.test.status;
+  }
+
+  if (transaction.skip) {
+    return 'skip';
+  }
+
+  return transaction.fail || (transaction.results && transaction.results.valid === false) ? 'fail' : 'pass';
+}
+
+function bodyString(body) {
+  if (body === undefined || body === null) {
+    return '';
+  }
+
+  return typeof body === 'string' ? body : JSON.stringify(body);
+}
+
+function httpMessage(message) {
+  if (!message) {
+    return null;
+  }
+
+  return {
+    statusCode: message.statusCode ? Number(message.statusCode) : undefined,
+    headers: message.headers || {},
+    body: bodyString(message.body),
+  };
+}
+
+function recordResult(transaction) {
+  const result = {
+    name: transaction.name,
+    operation: providerState(transaction).operation,
+    status: status(transaction),
+    durationMs: transaction.signetStartedAt ? Date.now() - transaction.signetStartedAt : 0,
+    request: {
+      method: transaction.request.method,
+      uri: transaction.request.uri,
+      headers: transaction.request.headers || {},
+      body: bodyString(transaction.request.body),
+    },
+    expected: httpMessage(transaction.expected),
+    actual: httpMessage(transaction.real),
+  };
+
+  if (result.status === 'fail') {
+    result.failures = failures(transaction);
+  }
+
+  results.push(result);
+}
+
+hooks.afterEach((transaction, done) => {
+  if (resultsPath) {
+    recordResult(transaction);
+  }
+
+  if (afterHook) {
+    runHook(afterHook, transaction);
+  }
+
+  if (stateSetupURL) {
+    postState(transaction, 'teardown', done);
+  } else {
+    done();
+  }
+});
+
+hooks.afterAll((transactions, done) => {
+  if (resultsPath) {
+    // transactions that were skipped never reach afterEach
+    transactions
+      .filter((transaction) => !results.some((result) => result.name === transaction.name))
+      .forEach((transaction) => recordResult(transaction));
+
+    require('fs').writeFileSync(resultsPath, JSON.stringify(results, null, 2));
+  }
+
+  done();
+});
+`))
+
+// CreateDreddHooks generates the javascript hookfile for dredd's --hookfiles option
+func CreateDreddHooks(h DreddHooks) ([]byte, error) {
+	requestHeaders := h.RequestHeaders
+	if requestHeaders == nil {
+		requestHeaders = map[string]string{}
+	}
+
+	values := map[string]string{}
+	settings := map[string]interface{}{
+		"StateSetupURL":  h.StateSetupURL,
+		"BeforeHook":     h.BeforeHook,
+		"AfterHook":      h.AfterHook,
+		"RequestHeaders": requestHeaders,
+		"RequestFilter":  h.RequestFilter,
+		"ResultsPath":    h.ResultsPath,
+	}
+
+	for key, setting := range settings {
+		jsonBytes, err := json.Marshal(setting)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = string(jsonBytes)
+	}
+
+	var script bytes.Buffer
+	err := dreddHooksTemplate.Execute(&script, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return script.Bytes(), nil
+}
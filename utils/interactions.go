@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+Exchange is one request and response captured between a consumer and a
+provider. Recordings made by signet proxy and imported traffic captures are
+both converted to exchanges, so they produce interactions the same way.
+*/
+type Exchange struct {
+	Method               string
+	Path                 string
+	Query                interface{}
+	RequestHeaders       map[string]interface{}
+	RequestBody          interface{}
+	RequestBodyEncoding  string
+	Status               int
+	ResponseHeaders      map[string]interface{}
+	ResponseBody         interface{}
+	ResponseBodyEncoding string
+}
+
+// only these headers are part of a consumer contract, all others are dropped
+var contractRequestHeaders = []string{"Content-Type", "Accept"}
+var contractResponseHeaders = []string{"Content-Type"}
+
+/*
+CreateInteractions converts exchanges to Pact interactions. Exchanges which
+produce identical interactions are only included once, and interactions that
+would share a description are numbered so that each description is unique.
+*/
+func CreateInteractions(exchanges []Exchange) []map[string]interface{} {
+	interactions := []map[string]interface{}{}
+	seen := map[string]bool{}
+	descriptions := map[string]int{}
+
+	for _, exchange := range exchanges {
+		interaction := createInteraction(exchange)
+
+		key, err := json.Marshal([]interface{}{interaction["request"], interaction["response"]})
+		if err == nil {
+			if seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+		}
+
+		description := interaction["description"].(string)
+		descriptions[description]++
+		if count := descriptions[description]; count > 1 {
+			interaction["description"] = fmt.Sprintf("%s (%d)", description, count)
+		}
+
+		interactions = append(interactions, interaction)
+	}
+
+	return interactions
+}
+
+// writes a consumer contract for the exchanges and returns the number of interactions written
+func CreatePactFromExchanges(exchanges []Exchange, pactPath, consumerName, providerName string) (int, error) {
+	interactions := CreateInteractions(exchanges)
+	if len(interactions) == 0 {
+		return 0, nil
+	}
+
+	pact := CreateDefaultPact(pactPath, consumerName, providerName)
+	pact["interactions"] = interactions
+
+	err := WritePact(pact, pactPath)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(interactions), nil
+}
+
+func createInteraction(exchange Exchange) map[string]interface{} {
+	interaction := map[string]interface{}{}
+
+	interaction["description"] = fmt.Sprintf("%s %s %d", exchange.Method, exchange.Path, exchange.Status)
+
+	contentType := headerValue(exchange.RequestHeaders, "Content-Type")
+	requestBody, requestEncoding := DecodeBody(exchange.RequestBody, contentType, exchange.RequestBodyEncoding)
+
+	op, ok := ParseGraphQLRequest(exchange.Method, exchange.Path, contentType, exchange.RequestBody, queryMap(exchange.Query))
+	if ok {
+		interaction["description"] = fmt.Sprintf("%s %s %s %d", exchange.Method, exchange.Path, op.Description(), exchange.Status)
+		requestBody, requestEncoding = op.Body(), ""
+	}
+
+	responseContentType := headerValue(exchange.ResponseHeaders, "Content-Type")
+	responseBody, responseEncoding := DecodeBody(exchange.ResponseBody, responseContentType, exchange.ResponseBodyEncoding)
+
+	request := map[string]interface{}{
+		"method":  exchange.Method,
+		"path":    exchange.Path,
+		"body":    requestBody,
+		"query":   exchange.Query,
+		"headers": filterHeaders(exchange.RequestHeaders, contractRequestHeaders),
+	}
+
+	if len(requestEncoding) != 0 {
+		request[BodyEncodingKey] = requestEncoding
+	}
+
+	response := map[string]interface{}{
+		"status":  exchange.Status,
+		"headers": filterHeaders(exchange.ResponseHeaders, contractResponseHeaders),
+		"body":    responseBody,
+	}
+
+	if len(responseEncoding) != 0 {
+		response[BodyEncodingKey] = responseEncoding
+	}
+
+	interaction["request"] = request
+	interaction["response"] = response
+
+	return interaction
+}
+
+func filterHeaders(headers map[string]interface{}, names []string) map[string]interface{} {
+	filtered := map[string]interface{}{}
+
+	for _, name := range names {
+		for key, value := range headers {
+			if strings.EqualFold(key, name) {
+				filtered[name] = value
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// header names are recorded with the casing sent by the client
+func headerValue(headers map[string]interface{}, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			str, _ := value.(string)
+			return str
+		}
+	}
+	return ""
+}
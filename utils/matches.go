@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// MbMatch is a request and response recorded by mountebank in a matches directory
+type MbMatch struct {
+	Timestamp string           `json:"timestamp"`
+	Request   *MbMatchRequest  `json:"request"`
+	Response  *MbMatchResponse `json:"response"`
+}
+
+type MbMatchRequest struct {
+	Method  string                 `json:"method"`
+	Path    string                 `json:"path"`
+	Query   map[string]interface{} `json:"query"`
+	Headers map[string]interface{} `json:"headers"`
+	Body    interface{}            `json:"body"`
+}
+
+type MbMatchResponse struct {
+	StatusCode json.RawMessage        `json:"statusCode"`
+	Headers    map[string]interface{} `json:"headers"`
+	Body       interface{}            `json:"body"`
+	Mode       string                 `json:"_mode"`
+}
+
+// MatchError reports a mountebank match file which could not be turned into an interaction
+type MatchError struct {
+	Path string
+	Err  error
+}
+
+func (e *MatchError) Error() string {
+	return "skipped invalid mountebank match file " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *MatchError) Unwrap() error {
+	return e.Err
+}
+
+func GetInteractions(stubsPath string) ([]map[string]interface{}, error) {
+	matchPaths, err := getRecordedMatchPaths(stubsPath)
+	if err != nil {
+		return []map[string]interface{}{}, err
+	}
+
+	// invalid match files are reported when the contract is written
+	exchanges, _ := loadMatchExchanges(matchPaths)
+
+	return CreateInteractions(exchanges), nil
+}
+
+/*
+LoadMatchesExchanges reads every request and response mountebank recorded
+under a stubs or matches directory. Match files which cannot be decoded are
+skipped and returned as MatchErrors, so one bad record does not lose the rest.
+*/
+func LoadMatchesExchanges(stubsPath string) ([]Exchange, []error, error) {
+	matchPaths, err := GetMatchPaths(stubsPath)
+	if err != nil {
+		return []Exchange{}, nil, err
+	}
+
+	exchanges, skipped := loadMatchExchanges(matchPaths)
+	return exchanges, skipped, nil
+}
+
+func ClearMatches(stubsPath string) error {
+	matchPaths, err := getRecordedMatchPaths(stubsPath)
+	if err != nil {
+		return err
+	}
+
+	for _, matchPath := range matchPaths {
+		err = os.Remove(matchPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func GetMatchPaths(stubsPath string) ([]string, error) {
+	matchPaths := []string{}
+
+	err := filepath.WalkDir(stubsPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && filepath.Base(filepath.Dir(path)) == "matches" {
+			matchPaths = append(matchPaths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return []string{}, errors.New("failed to read mountebank matches: " + err.Error())
+	}
+
+	return matchPaths, nil
+}
+
+// mountebank only creates the stubs directory once the proxy records its first request
+func getRecordedMatchPaths(stubsPath string) ([]string, error) {
+	_, err := os.Stat(stubsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+
+	return GetMatchPaths(stubsPath)
+}
+
+func loadMatchExchanges(matchPaths []string) ([]Exchange, []error) {
+	exchanges := []Exchange{}
+	skipped := []error{}
+
+	for _, matchPath := range matchPaths {
+		match, err := LoadMatch(matchPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // cleared while the contract was being written
+		}
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+
+		exchange, err := exchangeFromMatch(match)
+		if err != nil {
+			skipped = append(skipped, &MatchError{Path: matchPath, Err: err})
+			continue
+		}
+
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges, skipped
+}
+
+// LoadMatch decodes a mountebank match file, errors are MatchErrors naming the file
+func LoadMatch(matchPath string) (MbMatch, error) {
+	matchBytes, err := os.ReadFile(matchPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return MbMatch{}, err
+		}
+		return MbMatch{}, &MatchError{Path: matchPath, Err: err}
+	}
+
+	var match MbMatch
+	err = json.Unmarshal(matchBytes, &match)
+	if err != nil {
+		return MbMatch{}, &MatchError{Path: matchPath, Err: err}
+	}
+
+	return match, nil
+}
+
+func exchangeFromMatch(match MbMatch) (Exchange, error) {
+	if match.Request == nil {
+		return Exchange{}, errors.New("match has no request")
+	}
+
+	if match.Response == nil {
+		return Exchange{}, errors.New("match has no response")
+	}
+
+	if len(match.Request.Method) == 0 {
+		return Exchange{}, errors.New("request has no method")
+	}
+
+	if len(match.Request.Path) == 0 {
+		return Exchange{}, errors.New("request has no path")
+	}
+
+	status, err := parseStatusCode(match.Response.StatusCode)
+	if err != nil {
+		return Exchange{}, err
+	}
+
+	requestHeaders := match.Request.Headers
+	if requestHeaders == nil {
+		requestHeaders = map[string]interface{}{}
+	}
+
+	responseHeaders := match.Response.Headers
+	if responseHeaders == nil {
+		responseHeaders = map[string]interface{}{}
+	}
+
+	var query interface{}
+	if match.Request.Query != nil {
+		query = match.Request.Query
+	}
+
+	exchange := Exchange{
+		Method:          match.Request.Method,
+		Path:            match.Request.Path,
+		Query:           query,
+		RequestHeaders:  requestHeaders,
+		RequestBody:     match.Request.Body,
+		Status:          status,
+		ResponseHeaders: responseHeaders,
+		ResponseBody:    match.Response.Body,
+	}
+
+	// mountebank base64 encodes response bodies it proxies in binary mode
+	if match.Response.Mode == "binary" {
+		exchange.ResponseBodyEncoding = Base64Encoding
+	}
+
+	return exchange, nil
+}
+
+// mountebank writes the status code as a number, but stubs may also set it as a string
+func parseStatusCode(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 {
+		return 0, errors.New("response has no statusCode")
+	}
+
+	var statusStr string
+	if json.Unmarshal(raw, &statusStr) != nil {
+		statusStr = string(raw)
+	}
+
+	status, err := strconv.Atoi(statusStr)
+	if err != nil || status < 100 || status > 599 {
+		return 0, fmt.Errorf("response has an invalid statusCode %s", string(raw))
+	}
+
+	return status, nil
+}
@@ -0,0 +1,436 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type matcher struct {
+	Match string      `json:"match"`
+	Regex string      `json:"regex"`
+	Value interface{} `json:"value"`
+	Min   *int        `json:"min"`
+	Max   *int        `json:"max"`
+}
+
+type matchingRule struct {
+	path     []string
+	matchers []matcher
+}
+
+// responseMatcher compares an actual response to a contract response, using the contract's matching rules
+type responseMatcher struct {
+	bodyRules   []matchingRule
+	headerRules map[string][]matcher
+}
+
+/*
+MatchResponse returns a description of each way the actual response does not
+satisfy the expected response of a Pact interaction. Pact v2 and v3 matching
+rules are supported. Without a rule, values must be equal, objects may have
+keys which are not in the contract, and arrays must have the same length.
+*/
+func MatchResponse(expected map[string]interface{}, status int, headers http.Header, body []byte) []string {
+	m := newResponseMatcher(expected["matchingRules"])
+	mismatches := []string{}
+
+	expectedStatus, ok := expected["status"].(float64)
+	if ok && int(expectedStatus) != status {
+		mismatches = append(mismatches, fmt.Sprintf("status: expected %d but got %d", int(expectedStatus), status))
+	}
+
+	expectedHeaders, _ := expected["headers"].(map[string]interface{})
+	for _, name := range sortedKeys(expectedHeaders) {
+		mismatches = append(mismatches, m.matchHeader(name, headerString(expectedHeaders[name]), headers.Values(name))...)
+	}
+
+	expectedBody, hasBody := expected["body"]
+	if !hasBody || expectedBody == nil {
+		return mismatches
+	}
+
+	if expected[BodyEncodingKey] == Base64Encoding {
+		if expectedBody != base64.StdEncoding.EncodeToString(body) {
+			mismatches = append(mismatches, "body: binary body does not match the contract")
+		}
+		return mismatches
+	}
+
+	decodedBody, _ := DecodeBody(string(body), headers.Get("Content-Type"), "")
+
+	// decoded bodies are compared in the same form as contracts loaded from JSON
+	var actualBody interface{}
+	bodyBytes, err := json.Marshal(decodedBody)
+	if err == nil {
+		json.Unmarshal(bodyBytes, &actualBody)
+	}
+
+	m.compare([]string{"$"}, expectedBody, actualBody, &mismatches)
+
+	return mismatches
+}
+
+func newResponseMatcher(rules interface{}) *responseMatcher {
+	m := &responseMatcher{headerRules: map[string][]matcher{}}
+	rulesByCategory, _ := rules.(map[string]interface{})
+
+	for key, rule := range rulesByCategory {
+		switch {
+		// v3 rules are grouped by category
+		case key == "body":
+			bodyRules, _ := rule.(map[string]interface{})
+			for path, pathRule := range bodyRules {
+				m.bodyRules = append(m.bodyRules, matchingRule{parseRulePath(path), parseMatchers(pathRule)})
+			}
+		case key == "header":
+			headerRules, _ := rule.(map[string]interface{})
+			for name, headerRule := range headerRules {
+				m.headerRules[strings.ToLower(name)] = parseMatchers(headerRule)
+			}
+		// v2 rules are keyed by a path from the root of the response
+		case strings.HasPrefix(key, "$.body"):
+			path := "$" + strings.TrimPrefix(key, "$.body")
+			m.bodyRules = append(m.bodyRules, matchingRule{parseRulePath(path), parseMatchers(rule)})
+		case strings.HasPrefix(key, "$.headers."):
+			m.headerRules[strings.ToLower(strings.TrimPrefix(key, "$.headers."))] = parseMatchers(rule)
+		}
+	}
+
+	return m
+}
+
+// v3 rules have a list of matchers, v2 rules are a single matcher
+func parseMatchers(rule interface{}) []matcher {
+	ruleBytes, err := json.Marshal(rule)
+	if err != nil {
+		return nil
+	}
+
+	var v3Rule struct {
+		Matchers []matcher `json:"matchers"`
+	}
+	if json.Unmarshal(ruleBytes, &v3Rule) == nil && len(v3Rule.Matchers) != 0 {
+		return v3Rule.Matchers
+	}
+
+	var v2Rule matcher
+	if json.Unmarshal(ruleBytes, &v2Rule) != nil {
+		return nil
+	}
+	if len(v2Rule.Match) == 0 && len(v2Rule.Regex) != 0 {
+		v2Rule.Match = "regex"
+	}
+	if len(v2Rule.Match) == 0 && (v2Rule.Min != nil || v2Rule.Max != nil) {
+		v2Rule.Match = "type"
+	}
+	return []matcher{v2Rule}
+}
+
+var rulePathToken = regexp.MustCompile(`\.([^.\[\]]+)|\[(\d+|\*)\]|\['([^']*)'\]`)
+
+// parses a JSON path like $.users[*].name into ["$", "users", "*", "name"]
+func parseRulePath(path string) []string {
+	tokens := []string{"$"}
+	for _, match := range rulePathToken.FindAllStringSubmatch(strings.TrimPrefix(path, "$"), -1) {
+		switch {
+		case len(match[1]) != 0:
+			tokens = append(tokens, match[1])
+		case len(match[2]) != 0:
+			tokens = append(tokens, match[2])
+		default:
+			tokens = append(tokens, match[3])
+		}
+	}
+	return tokens
+}
+
+func rulePathMatches(rulePath, path []string) bool {
+	if len(rulePath) != len(path) {
+		return false
+	}
+	for i := range rulePath {
+		if rulePath[i] != "*" && rulePath[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func wildcards(rulePath []string) int {
+	count := 0
+	for _, token := range rulePath {
+		if token == "*" {
+			count++
+		}
+	}
+	return count
+}
+
+/*
+matchersFor returns the matchers of the most specific rule for path. A type
+rule also applies to everything below its path, like it does in Pact.
+*/
+func (m *responseMatcher) matchersFor(path []string) []matcher {
+	var best *matchingRule
+	for i, rule := range m.bodyRules {
+		if rulePathMatches(rule.path, path) && (best == nil || wildcards(rule.path) < wildcards(best.path)) {
+			best = &m.bodyRules[i]
+		}
+	}
+	if best != nil {
+		return best.matchers
+	}
+
+	for depth := len(path) - 1; depth > 0; depth-- {
+		for _, rule := range m.bodyRules {
+			if rulePathMatches(rule.path, path[:depth]) && cascades(rule.matchers) {
+				return []matcher{{Match: "type"}}
+			}
+		}
+	}
+
+	return nil
+}
+
+func cascades(matchers []matcher) bool {
+	for _, matcher := range matchers {
+		if matcher.Match == "type" {
+			return true
+		}
+	}
+	return false
+}
+
+func describePath(path []string) string {
+	description := path[0]
+	for _, token := range path[1:] {
+		if _, err := strconv.Atoi(token); err == nil {
+			description += "[" + token + "]"
+		} else {
+			description += "." + token
+		}
+	}
+	return "body " + description
+}
+
+func (m *responseMatcher) compare(path []string, expected, actual interface{}, mismatches *[]string) {
+	matchers := m.matchersFor(path)
+	if len(matchers) == 0 {
+		m.compareEqual(path, expected, actual, mismatches)
+		return
+	}
+
+	for _, matcher := range matchers {
+		m.applyMatcher(path, matcher, expected, actual, mismatches)
+	}
+}
+
+func (m *responseMatcher) compareEqual(path []string, expected, actual interface{}, mismatches *[]string) {
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualValue, ok := actual.(map[string]interface{})
+		if !ok {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s: expected an object but got %s", describePath(path), describeValue(actual)))
+			return
+		}
+		m.compareKeys(path, expectedValue, actualValue, mismatches)
+	case []interface{}:
+		actualValue, ok := actual.([]interface{})
+		if !ok {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s: expected an array but got %s", describePath(path), describeValue(actual)))
+			return
+		}
+		if len(actualValue) != len(expectedValue) {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s: expected %d items but got %d", describePath(path), len(expectedValue), len(actualValue)))
+			return
+		}
+		for i := range expectedValue {
+			m.compare(append(path[:len(path):len(path)], strconv.Itoa(i)), expectedValue[i], actualValue[i], mismatches)
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s: expected %s but got %s", describePath(path), describeValue(expected), describeValue(actual)))
+		}
+	}
+}
+
+func (m *responseMatcher) compareKeys(path []string, expected, actual map[string]interface{}, mismatches *[]string) {
+	for _, key := range sortedKeys(expected) {
+		expectedValue := expected[key]
+		actualValue, ok := actual[key]
+		if !ok {
+			*mismatches = append(*mismatches, fmt.Sprintf("%s: missing key %q", describePath(path), key))
+			continue
+		}
+		m.compare(append(path[:len(path):len(path)], key), expectedValue, actualValue, mismatches)
+	}
+}
+
+func (m *responseMatcher) applyMatcher(path []string, rule matcher, expected, actual interface{}, mismatches *[]string) {
+	mismatch := func(format string, args ...interface{}) {
+		*mismatches = append(*mismatches, describePath(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch rule.Match {
+	case "type", "":
+		if jsonType(expected) != jsonType(actual) {
+			mismatch("expected a value of type %s but got %s", jsonType(expected), describeValue(actual))
+			return
+		}
+
+		switch expectedValue := expected.(type) {
+		case map[string]interface{}:
+			m.compareKeys(path, expectedValue, actual.(map[string]interface{}), mismatches)
+		case []interface{}:
+			actualValue := actual.([]interface{})
+			if rule.Min != nil && len(actualValue) < *rule.Min {
+				mismatch("expected at least %d items but got %d", *rule.Min, len(actualValue))
+			}
+			if rule.Max != nil && len(actualValue) > *rule.Max {
+				mismatch("expected at most %d items but got %d", *rule.Max, len(actualValue))
+			}
+			if len(expectedValue) == 0 {
+				return
+			}
+			// like Pact, every item is matched against the first item in the contract
+			for i := range actualValue {
+				m.compare(append(path[:len(path):len(path)], strconv.Itoa(i)), expectedValue[0], actualValue[i], mismatches)
+			}
+		}
+	case "regex":
+		str, ok := scalarString(actual)
+		re, err := regexp.Compile(rule.Regex)
+		if !ok || err != nil || !re.MatchString(str) {
+			mismatch("expected a value matching %q but got %s", rule.Regex, describeValue(actual))
+		}
+	case "equality":
+		m.compareEqual(path, expected, actual, mismatches)
+	case "include":
+		str, ok := actual.(string)
+		if !ok || !strings.Contains(str, fmt.Sprint(rule.Value)) {
+			mismatch("expected a string including %q but got %s", fmt.Sprint(rule.Value), describeValue(actual))
+		}
+	case "integer":
+		number, ok := actual.(float64)
+		if !ok || number != float64(int64(number)) {
+			mismatch("expected an integer but got %s", describeValue(actual))
+		}
+	case "decimal", "number":
+		if _, ok := actual.(float64); !ok {
+			mismatch("expected a number but got %s", describeValue(actual))
+		}
+	case "boolean":
+		if _, ok := actual.(bool); !ok {
+			mismatch("expected a boolean but got %s", describeValue(actual))
+		}
+	case "null":
+		if actual != nil {
+			mismatch("expected null but got %s", describeValue(actual))
+		}
+	case "date", "time", "timestamp", "datetime":
+		if _, ok := actual.(string); !ok {
+			mismatch("expected a %s string but got %s", rule.Match, describeValue(actual))
+		}
+	default:
+		mismatch("unsupported matcher %q", rule.Match)
+	}
+}
+
+func (m *responseMatcher) matchHeader(name, expected string, actualValues []string) []string {
+	if len(actualValues) == 0 {
+		return []string{fmt.Sprintf("header %s: missing", name)}
+	}
+	actual := strings.Join(actualValues, ", ")
+
+	for _, rule := range m.headerRules[strings.ToLower(name)] {
+		if rule.Match == "regex" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil || !re.MatchString(actual) {
+				return []string{fmt.Sprintf("header %s: expected a value matching %q but got %q", name, rule.Regex, actual)}
+			}
+			return nil
+		}
+	}
+
+	// parameters like charset are often added by frameworks, so only the media types are compared
+	if strings.EqualFold(name, "Content-Type") {
+		expectedType, _, expectedErr := mime.ParseMediaType(expected)
+		actualType, _, actualErr := mime.ParseMediaType(actual)
+		if expectedErr == nil && actualErr == nil && expectedType == actualType {
+			return nil
+		}
+	}
+
+	if strings.TrimSpace(expected) != strings.TrimSpace(actual) {
+		return []string{fmt.Sprintf("header %s: expected %q but got %q", name, expected, actual)}
+	}
+	return nil
+}
+
+// contract headers are strings, or lists of strings for repeated headers
+func headerString(value interface{}) string {
+	if values, ok := value.([]interface{}); ok {
+		strs := []string{}
+		for _, val := range values {
+			strs = append(strs, fmt.Sprint(val))
+		}
+		return strings.Join(strs, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func scalarString(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case string:
+		return typed, true
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(typed), true
+	}
+	return "", false
+}
+
+func describeValue(value interface{}) string {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(valueBytes)
+}
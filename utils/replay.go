@@ -0,0 +1,479 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsumerContract is a consumer's Pact contract for a provider, as returned by the broker
+type ConsumerContract struct {
+	ConsumerName    string                 `json:"consumerName"`
+	ConsumerVersion string                 `json:"consumerVersion"`
+	ProviderName    string                 `json:"-"`
+	Contract        map[string]interface{} `json:"contract"`
+}
+
+func ParseBrokerContracts(contractsBytes []byte) ([]ConsumerContract, error) {
+	var contracts []ConsumerContract
+	err := json.Unmarshal(contractsBytes, &contracts)
+	if err != nil {
+		return nil, errors.New("failed to parse contracts from the broker: " + err.Error())
+	}
+
+	return contracts, nil
+}
+
+// LoadConsumerContract reads a local Pact contract file, the same file a consumer publishes to the broker
+func LoadConsumerContract(path string) (ConsumerContract, error) {
+	contractBytes, err := os.ReadFile(path)
+	if err != nil {
+		return ConsumerContract{}, errors.New("failed to read contract " + path + ": " + err.Error())
+	}
+
+	var contract map[string]interface{}
+	err = json.Unmarshal(contractBytes, &contract)
+	if err != nil {
+		return ConsumerContract{}, errors.New("failed to parse contract " + path + ": " + err.Error())
+	}
+
+	participantName := func(key string) string {
+		participant, _ := contract[key].(map[string]interface{})
+		participantName, _ := participant["name"].(string)
+		return participantName
+	}
+
+	return ConsumerContract{
+		ConsumerName: participantName("consumer"),
+		ProviderName: participantName("provider"),
+		Contract:     contract,
+	}, nil
+}
+
+/*
+ContractVerifier replays the interactions of consumer contracts against a
+running provider. The same hooks as spec verification are supported: the
+provider state of each interaction is sent to the state setup URL, and
+requests go through the request headers and request filter.
+*/
+type ContractVerifier struct {
+	ProviderURL string
+	Hooks       DreddHooks
+	Client      *http.Client
+}
+
+// used when a verifier has no client, so that a provider which never responds fails the interaction
+var defaultReplayClient = &http.Client{Timeout: 30 * time.Second}
+
+func (v ContractVerifier) VerifyContract(contract ConsumerContract) []VerificationResult {
+	interactions, _ := contract.Contract["interactions"].([]interface{})
+	results := []VerificationResult{}
+
+	for _, interaction := range interactions {
+		interactionMap, ok := interaction.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		results = append(results, v.VerifyInteraction(contract.ConsumerName, interactionMap))
+	}
+
+	return results
+}
+
+func (v ContractVerifier) VerifyInteraction(consumer string, interaction map[string]interface{}) VerificationResult {
+	request, _ := interaction["request"].(map[string]interface{})
+	response, _ := interaction["response"].(map[string]interface{})
+
+	reportedRequest, err := contractRequest(request)
+	result := VerificationResult{
+		Consumer:  consumer,
+		Name:      fmt.Sprint(interaction["description"]),
+		Operation: reportedRequest.Method + " " + fmt.Sprint(request["path"]),
+		Request:   reportedRequest,
+		Expected:  contractResponse(response),
+	}
+	if err != nil {
+		return result.failed(err.Error())
+	}
+
+	return v.exchange(result, providerStateName(interaction), func(status int, headers http.Header, body []byte) []string {
+		return MatchResponse(response, status, headers, body)
+	})
+}
+
+/*
+exchange sends the result's request to the provider, wrapped in the hooks and
+provider state setup, and records the response. check returns the reasons the
+response is not acceptable.
+*/
+func (v ContractVerifier) exchange(result VerificationResult, state string, check func(int, http.Header, []byte) []string) VerificationResult {
+	env := append(os.Environ(), "SIGNET_STATE="+state, "SIGNET_OPERATION="+result.Operation)
+
+	if len(v.Hooks.BeforeHook) != 0 {
+		_, err := runShellHook(v.Hooks.BeforeHook, env, nil)
+		if err != nil {
+			return result.failed(err.Error())
+		}
+	}
+
+	if len(v.Hooks.StateSetupURL) != 0 {
+		err := v.postState(state, result.Operation, "setup")
+		if err != nil {
+			return result.failed(err.Error())
+		}
+	}
+
+	result = v.sendRequest(result, check, env)
+
+	if len(v.Hooks.AfterHook) != 0 {
+		_, err := runShellHook(v.Hooks.AfterHook, env, nil)
+		if err != nil && result.Status == "pass" {
+			result = result.failed(err.Error())
+		}
+	}
+
+	if len(v.Hooks.StateSetupURL) != 0 {
+		err := v.postState(state, result.Operation, "teardown")
+		if err != nil && result.Status == "pass" {
+			result = result.failed(err.Error())
+		}
+	}
+
+	return result
+}
+
+func (v ContractVerifier) sendRequest(result VerificationResult, check func(int, http.Header, []byte) []string, env []string) VerificationResult {
+	for name, value := range v.Hooks.RequestHeaders {
+		setReportedHeader(result.Request.Headers, name, value)
+	}
+
+	baseURL := v.ProviderURL
+	if len(v.Hooks.RequestFilter) != 0 {
+		filtered, filteredURL, err := filterContractRequest(v.Hooks.RequestFilter, env, result.Request, baseURL)
+		if err != nil {
+			return result.failed(err.Error())
+		}
+		result.Request, baseURL = filtered, filteredURL
+	}
+
+	httpRequest, err := http.NewRequest(result.Request.Method, strings.TrimRight(baseURL, "/")+result.Request.URI, strings.NewReader(result.Request.Body))
+	if err != nil {
+		return result.failed(err.Error())
+	}
+	for name, value := range result.Request.Headers {
+		httpRequest.Header.Set(name, fmt.Sprint(value))
+	}
+
+	client := v.Client
+	if client == nil {
+		client = defaultReplayClient
+	}
+
+	start := time.Now()
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return result.failed("request failed: " + err.Error())
+	}
+	defer httpResponse.Body.Close()
+
+	body, err := io.ReadAll(httpResponse.Body)
+	result.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		return result.failed("failed to read response: " + err.Error())
+	}
+
+	headers := map[string]interface{}{}
+	for name := range httpResponse.Header {
+		headers[name] = httpResponse.Header.Get(name)
+	}
+	result.Actual = &ReportedMessage{StatusCode: httpResponse.StatusCode, Headers: headers, Body: string(body)}
+
+	mismatches := check(httpResponse.StatusCode, httpResponse.Header, body)
+	if len(mismatches) != 0 {
+		return result.failed(mismatches...)
+	}
+
+	result.Status = "pass"
+	return result
+}
+
+func (r VerificationResult) failed(failures ...string) VerificationResult {
+	r.Status = "fail"
+	r.Failures = append(r.Failures, failures...)
+	return r
+}
+
+// the state setup request has the same body as in spec verification
+func (v ContractVerifier) postState(state, operation, action string) error {
+	body, err := json.Marshal(map[string]string{"state": state, "operation": operation, "action": action})
+	if err != nil {
+		return err
+	}
+
+	client := v.Client
+	if client == nil {
+		client = defaultReplayClient
+	}
+
+	resp, err := client.Post(v.Hooks.StateSetupURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.New("provider state " + action + " failed: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return errors.New("provider state " + action + " failed with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+// Pact v3 contracts have a list of providerStates, v2 contracts a single providerState
+func providerStateName(interaction map[string]interface{}) string {
+	if states, ok := interaction["providerStates"].([]interface{}); ok && len(states) != 0 {
+		if state, ok := states[0].(map[string]interface{}); ok {
+			return fmt.Sprint(state["name"])
+		}
+	}
+
+	if state, ok := interaction["providerState"].(string); ok {
+		return state
+	}
+
+	return fmt.Sprint(interaction["description"])
+}
+
+func runShellHook(command string, env []string, input []byte) ([]byte, error) {
+	hookCmd := exec.Command("sh", "-c", command)
+	hookCmd.Env = env
+	if input != nil {
+		hookCmd.Stdin = bytes.NewReader(input)
+	}
+
+	var stderr bytes.Buffer
+	hookCmd.Stderr = &stderr
+
+	output, err := hookCmd.Output()
+	if err != nil {
+		return nil, errors.New("hook \"" + command + "\" failed: " + strings.TrimSpace(stderr.String()+" "+err.Error()))
+	}
+	return output, nil
+}
+
+// filtered requests use the same JSON as the request filter in spec verification
+func filterContractRequest(command string, env []string, request *ReportedMessage, baseURL string) (*ReportedMessage, string, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	port, _ := strconv.Atoi(parsedURL.Port())
+	filterRequest := map[string]interface{}{
+		"method":   request.Method,
+		"uri":      parsedURL.Path + request.URI,
+		"headers":  request.Headers,
+		"body":     request.Body,
+		"protocol": parsedURL.Scheme + ":",
+		"host":     parsedURL.Hostname(),
+		"port":     port,
+	}
+
+	input, err := json.Marshal(filterRequest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	output, err := runShellHook(command, env, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(output, &filterRequest)
+	if err != nil {
+		return nil, "", errors.New("request filter \"" + command + "\" did not print a JSON request: " + err.Error())
+	}
+
+	filtered := &ReportedMessage{
+		Method:  fmt.Sprint(filterRequest["method"]),
+		URI:     fmt.Sprint(filterRequest["uri"]),
+		Headers: map[string]interface{}{},
+		Body:    fmt.Sprint(filterRequest["body"]),
+	}
+	if headers, ok := filterRequest["headers"].(map[string]interface{}); ok {
+		filtered.Headers = headers
+	}
+
+	filteredURL := strings.TrimSuffix(fmt.Sprint(filterRequest["protocol"]), ":") + "://" + fmt.Sprint(filterRequest["host"])
+	if port, ok := filterRequest["port"].(float64); ok && port != 0 {
+		filteredURL += ":" + strconv.Itoa(int(port))
+	}
+
+	return filtered, filteredURL, nil
+}
+
+func setReportedHeader(headers map[string]interface{}, name, value string) {
+	for existing := range headers {
+		if strings.EqualFold(existing, name) {
+			delete(headers, existing)
+		}
+	}
+	headers[name] = value
+}
+
+/*
+contractRequest builds the request to send from a contract request. Bodies
+are encoded according to their Content-Type, reversing DecodeBody.
+*/
+func contractRequest(request map[string]interface{}) (*ReportedMessage, error) {
+	reported := &ReportedMessage{
+		Method:  strings.ToUpper(fmt.Sprint(request["method"])),
+		URI:     fmt.Sprint(request["path"]),
+		Headers: map[string]interface{}{},
+	}
+
+	if headers, ok := request["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			reported.Headers[name] = headerString(value)
+		}
+	}
+
+	query := contractQuery(request["query"])
+	if len(query) != 0 {
+		reported.URI += "?" + query
+	}
+
+	body, hasBody := request["body"]
+	if !hasBody || body == nil {
+		return reported, nil
+	}
+
+	contentType := headerValue(reported.Headers, "Content-Type")
+	encodedBody, encodedContentType, err := encodeContractBody(body, contentType, request[BodyEncodingKey] == Base64Encoding)
+	if err != nil {
+		return reported, errors.New("failed to build request body: " + err.Error())
+	}
+
+	reported.Body = encodedBody
+	if encodedContentType != contentType {
+		setReportedHeader(reported.Headers, "Content-Type", encodedContentType)
+	}
+
+	return reported, nil
+}
+
+// v3 queries are maps of names to values or lists of values, v2 queries are strings
+func contractQuery(query interface{}) string {
+	switch typed := query.(type) {
+	case string:
+		return typed
+	case map[string]interface{}:
+		values := url.Values{}
+		for name, value := range typed {
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					values.Add(name, fmt.Sprint(item))
+				}
+			} else {
+				values.Add(name, fmt.Sprint(value))
+			}
+		}
+		return values.Encode()
+	}
+	return ""
+}
+
+func encodeContractBody(body interface{}, contentType string, base64Encoded bool) (string, string, error) {
+	if str, ok := body.(string); ok {
+		if base64Encoded {
+			decoded, err := base64.StdEncoding.DecodeString(str)
+			return string(decoded), contentType, err
+		}
+		return str, contentType, nil
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		fields, _ := body.(map[string]interface{})
+		return contractQuery(fields), contentType, nil
+	case mediaType == "multipart/form-data":
+		return encodeMultipart(body)
+	default:
+		bodyBytes, err := json.Marshal(body)
+		if len(contentType) == 0 {
+			contentType = "application/json"
+		}
+		return string(bodyBytes), contentType, err
+	}
+}
+
+// a new boundary is generated, so the Content-Type is replaced
+func encodeMultipart(body interface{}) (string, string, error) {
+	parts, _ := body.([]interface{})
+
+	var encoded bytes.Buffer
+	writer := multipart.NewWriter(&encoded)
+
+	for _, part := range parts {
+		partMap, ok := part.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		partContentType := fmt.Sprint(partMap["contentType"])
+		partBody, _, err := encodeContractBody(partMap["body"], partContentType, partMap[BodyEncodingKey] == Base64Encoding)
+		if err != nil {
+			return "", "", err
+		}
+
+		disposition := fmt.Sprintf(`form-data; name=%q`, fmt.Sprint(partMap["name"]))
+		if filename, ok := partMap["filename"].(string); ok {
+			disposition += fmt.Sprintf(`; filename=%q`, filename)
+		}
+
+		header := map[string][]string{
+			"Content-Disposition": {disposition},
+			"Content-Type":        {partContentType},
+		}
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return "", "", err
+		}
+		partWriter.Write([]byte(partBody))
+	}
+
+	err := writer.Close()
+	return encoded.String(), writer.FormDataContentType(), err
+}
+
+func contractResponse(response map[string]interface{}) *ReportedMessage {
+	reported := &ReportedMessage{Headers: map[string]interface{}{}}
+
+	if status, ok := response["status"].(float64); ok {
+		reported.StatusCode = int(status)
+	}
+	if headers, ok := response["headers"].(map[string]interface{}); ok {
+		reported.Headers = headers
+	}
+	if body, ok := response["body"]; ok && body != nil {
+		if str, ok := body.(string); ok {
+			reported.Body = str
+		} else {
+			bodyBytes, _ := json.Marshal(body)
+			reported.Body = string(bodyBytes)
+		}
+	}
+
+	return reported
+}
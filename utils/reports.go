@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VerificationResult is the outcome of one spec operation/response, as recorded by the dredd hooks
+type VerificationResult struct {
+	// Consumer is set when a consumer contract, rather than the spec, was verified
+	Consumer   string           `json:"consumer,omitempty"`
+	Name       string           `json:"name"`
+	Operation  string           `json:"operation"`
+	Status     string           `json:"status"`
+	DurationMs int64            `json:"durationMs"`
+	Request    *ReportedMessage `json:"request"`
+	Expected   *ReportedMessage `json:"expected"`
+	Actual     *ReportedMessage `json:"actual"`
+	Failures   []string         `json:"failures,omitempty"`
+}
+
+type ReportedMessage struct {
+	Method     string                 `json:"method,omitempty"`
+	URI        string                 `json:"uri,omitempty"`
+	StatusCode int                    `json:"statusCode,omitempty"`
+	Headers    map[string]interface{} `json:"headers"`
+	Body       string                 `json:"body"`
+}
+
+type VerificationReport struct {
+	Provider   string               `json:"provider"`
+	Version    string               `json:"version"`
+	Passed     int                  `json:"passed"`
+	Failed     int                  `json:"failed"`
+	Skipped    int                  `json:"skipped"`
+	DurationMs int64                `json:"durationMs"`
+	Results    []VerificationResult `json:"results"`
+}
+
+// the report formats accepted by signet test --report
+var ReportFormats = []string{"junit", "json", "coverage"}
+
+func LoadVerificationResults(resultsPath string) ([]VerificationResult, error) {
+	resultsBytes, err := os.ReadFile(resultsPath)
+	if err != nil {
+		return nil, errors.New("no verification results were recorded: " + err.Error())
+	}
+
+	var results []VerificationResult
+	err = json.Unmarshal(resultsBytes, &results)
+	if err != nil {
+		return nil, errors.New("failed to parse verification results: " + err.Error())
+	}
+
+	return results, nil
+}
+
+func CreateVerificationReport(results []VerificationResult, provider, version string) VerificationReport {
+	report := VerificationReport{Provider: provider, Version: version, Results: results}
+
+	for _, result := range results {
+		switch result.Status {
+		case "pass":
+			report.Passed++
+		case "fail":
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+		report.DurationMs += result.DurationMs
+	}
+
+	return report
+}
+
+// FailingOperations summarizes each failing operation/response with the reasons it failed
+func FailingOperations(results []VerificationResult) []string {
+	failing := []string{}
+	for _, result := range results {
+		if result.Status != "fail" {
+			continue
+		}
+
+		summary := result.Name
+		if len(result.Failures) != 0 {
+			summary += ": " + strings.Join(result.Failures, "; ")
+		}
+		failing = append(failing, summary)
+	}
+	return failing
+}
+
+var dreddFailLine = regexp.MustCompile(`(?m)^fail: (.+?)(?: duration: \S+)?$`)
+
+// FailingOperationsFromOutput is used when the hooks did not record any results, ex. if dredd crashed
+func FailingOperationsFromOutput(testOutput string) []string {
+	failing := []string{}
+	for _, match := range dreddFailLine.FindAllStringSubmatch(testOutput, -1) {
+		failing = append(failing, match[1])
+	}
+	return failing
+}
+
+func WriteJSONReport(report VerificationReport, reportPath string) error {
+	reportBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(reportPath, reportBytes, 0644)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+
+	durationMs int64
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+/*
+WriteJUnitReport writes one test suite for the provider, with a test case per
+operation/response. When consumer contracts were verified, there is a test
+suite per consumer instead, with a test case per interaction. The request is
+attached as system-out, and failing test cases include the expected and
+actual responses.
+*/
+func WriteJUnitReport(report VerificationReport, reportPath string) error {
+	suites := []junitTestSuite{}
+	suiteIndexes := map[string]int{}
+
+	for _, result := range report.Results {
+		suiteName := report.Provider
+		if len(result.Consumer) != 0 {
+			suiteName = result.Consumer + " -> " + report.Provider
+		}
+
+		i, ok := suiteIndexes[suiteName]
+		if !ok {
+			i = len(suites)
+			suiteIndexes[suiteName] = i
+			suites = append(suites, junitTestSuite{Name: suiteName})
+		}
+		suite := &suites[i]
+
+		testCase := junitTestCase{
+			ClassName: suiteName + "." + result.Operation,
+			Name:      result.Name,
+			Time:      junitSeconds(result.DurationMs),
+			SystemOut: describeMessage("Request", result.Request),
+		}
+
+		suite.Tests++
+		suite.durationMs += result.DurationMs
+
+		switch result.Status {
+		case "pass":
+		case "fail":
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: strings.Join(result.Failures, "; "),
+				Text: strings.Join(result.Failures, "\n") + "\n\n" +
+					describeMessage("Expected", result.Expected) + "\n" +
+					describeMessage("Actual", result.Actual),
+			}
+		default:
+			suite.Skipped++
+			testCase.Skipped = &struct{}{}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for i := range suites {
+		suites[i].Time = junitSeconds(suites[i].durationMs)
+	}
+
+	reportBytes, err := xml.MarshalIndent(junitTestSuites{Suites: suites}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(reportPath, append([]byte(xml.Header), reportBytes...), 0644)
+}
+
+func junitSeconds(durationMs int64) string {
+	return strconv.FormatFloat(float64(durationMs)/1000, 'f', 3, 64)
+}
+
+func describeMessage(title string, message *ReportedMessage) string {
+	if message == nil {
+		return title + ": none\n"
+	}
+
+	var description strings.Builder
+	description.WriteString(title + ":")
+
+	if len(message.Method) != 0 {
+		description.WriteString(" " + message.Method + " " + message.URI)
+	}
+	if message.StatusCode != 0 {
+		description.WriteString(" " + strconv.Itoa(message.StatusCode))
+	}
+	description.WriteString("\n")
+
+	headersBytes, err := json.Marshal(message.Headers)
+	if err == nil && len(message.Headers) != 0 {
+		description.WriteString("headers: " + string(headersBytes) + "\n")
+	}
+	if len(message.Body) != 0 {
+		description.WriteString("body: " + message.Body + "\n")
+	}
+
+	return description.String()
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+/*
+ProxyRoute maps traffic received by signet proxy to one provider. A route
+matches a request when the request path starts with PathPrefix and the Host
+header names Host. An empty PathPrefix or Host matches any request.
+*/
+type ProxyRoute struct {
+	PathPrefix   string `mapstructure:"path-prefix"`
+	Host         string `mapstructure:"host"`
+	Target       string `mapstructure:"target"`
+	ProviderName string `mapstructure:"provider-name"`
+	Path         string `mapstructure:"path"`
+}
+
+func (route ProxyRoute) Matches(requestPath, host string) bool {
+	if len(route.PathPrefix) != 0 && !strings.HasPrefix(requestPath, route.PathPrefix) {
+		return false
+	}
+
+	if len(route.Host) != 0 {
+		hostname, _, err := net.SplitHostPort(host)
+		if err != nil {
+			hostname = host
+		}
+
+		if !strings.EqualFold(hostname, route.Host) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mountebank predicates which select the same requests as Matches
+func (route ProxyRoute) Predicates() []map[string]interface{} {
+	predicates := []map[string]interface{}{}
+
+	if len(route.PathPrefix) != 0 {
+		predicates = append(predicates, map[string]interface{}{
+			"startsWith": map[string]interface{}{"path": route.PathPrefix},
+		})
+	}
+
+	if len(route.Host) != 0 {
+		predicates = append(predicates, map[string]interface{}{
+			"matches": map[string]interface{}{
+				"headers": map[string]interface{}{"Host": "^" + regexp.QuoteMeta(route.Host) + "(:\\d+)?$"},
+			},
+		})
+	}
+
+	return predicates
+}
+
+func ValidateProxyRoutes(routes []ProxyRoute) error {
+	for i, route := range routes {
+		if len(route.PathPrefix) == 0 && len(route.Host) == 0 {
+			return fmt.Errorf("proxy route %d must set a path-prefix or a host", i+1)
+		}
+
+		if len(route.Target) == 0 {
+			return fmt.Errorf("proxy route %d has no target", i+1)
+		}
+
+		if len(route.ProviderName) == 0 {
+			return fmt.Errorf("proxy route %d has no provider-name", i+1)
+		}
+
+		if len(route.Path) == 0 {
+			return fmt.Errorf("proxy route %d has no path", i+1)
+		}
+	}
+
+	return nil
+}
+
+// index of the first route matching the request, or -1 if none match
+func routeFor(routes []ProxyRoute, requestPath, host string) int {
+	for i, route := range routes {
+		if route.Matches(requestPath, host) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+CreatePacts writes one consumer contract per route from the interactions
+recorded by mountebank, and returns the paths of the contracts it wrote.
+Routes which did not record any interactions do not get a contract. Match
+files which could not be decoded are skipped and returned as MatchErrors.
+*/
+func CreatePacts(stubsPath string, consumerName string, routes []ProxyRoute) ([]string, []error, error) {
+	if len(routes) == 0 {
+		return nil, nil, errors.New("no proxy routes to write contracts for")
+	}
+
+	matchPaths, err := getRecordedMatchPaths(stubsPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exchanges, skipped := loadMatchExchanges(matchPaths)
+
+	routeExchanges := make([][]Exchange, len(routes))
+
+	for _, exchange := range exchanges {
+		i := routeFor(routes, exchange.Path, headerValue(exchange.RequestHeaders, "Host"))
+		if i == -1 {
+			continue
+		}
+
+		routeExchanges[i] = append(routeExchanges[i], exchange)
+	}
+
+	writtenPaths := []string{}
+
+	for i, route := range routes {
+		count, err := CreatePactFromExchanges(routeExchanges[i], route.Path, consumerName, route.ProviderName)
+		if err != nil {
+			return writtenPaths, skipped, err
+		}
+
+		if count != 0 {
+			writtenPaths = append(writtenPaths, route.Path)
+		}
+	}
+
+	return writtenPaths, skipped, nil
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the methods an OpenAPI path item can have, in the order operations are verified
+var specMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// SerialExtension puts operations in a group that is verified one operation at a time
+const SerialExtension = "x-signet-serial"
+
+// OrderExtension orders the operations within a serial group, lowest first
+const OrderExtension = "x-signet-order"
+
+type SpecOperation struct {
+	Path   string
+	Method string
+	// SerialGroup is set from x-signet-serial. Operations in the same group
+	// are verified one at a time, ordered by x-signet-order.
+	SerialGroup string
+	Order       float64
+	Definition  map[string]interface{}
+}
+
+func (op SpecOperation) String() string {
+	return strings.ToUpper(op.Method) + " " + op.Path
+}
+
+// LoadOpenAPISpec parses a JSON or YAML OpenAPI spec into a map
+func LoadOpenAPISpec(specPath string) (map[string]interface{}, error) {
+	specBytes, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseOpenAPISpec(specBytes)
+}
+
+/*
+ParseOpenAPISpec parses a JSON or YAML OpenAPI spec. The format is detected
+from the content, because specs fetched from the broker are always written
+to spec.json.
+*/
+func ParseOpenAPISpec(specBytes []byte) (map[string]interface{}, error) {
+	var spec map[string]interface{}
+
+	if json.Unmarshal(specBytes, &spec) != nil {
+		var parsed interface{}
+		err := yaml.Unmarshal(specBytes, &parsed)
+		if err != nil {
+			return nil, errors.New("failed to parse spec: " + err.Error())
+		}
+
+		spec, _ = normalizeYAML(parsed).(map[string]interface{})
+	}
+
+	if spec == nil {
+		return nil, errors.New("spec is not an OpenAPI document")
+	}
+
+	if _, ok := spec["paths"].(map[string]interface{}); !ok {
+		return nil, errors.New("spec has no paths")
+	}
+
+	return spec, nil
+}
+
+// yaml.v2 decodes objects as map[interface{}]interface{}, which cannot be written as JSON
+func normalizeYAML(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, val := range typed {
+			normalized[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return normalized
+	case []interface{}:
+		for i, val := range typed {
+			typed[i] = normalizeYAML(val)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+/*
+SpecOperations lists the operations in a spec, sorted by path and then
+method, so that the order does not depend on how the spec was written.
+*/
+func SpecOperations(spec map[string]interface{}) []SpecOperation {
+	paths, _ := spec["paths"].(map[string]interface{})
+
+	pathNames := []string{}
+	for pathName := range paths {
+		pathNames = append(pathNames, pathName)
+	}
+	sort.Strings(pathNames)
+
+	operations := []SpecOperation{}
+	for _, pathName := range pathNames {
+		pathItem, _ := paths[pathName].(map[string]interface{})
+
+		for _, method := range specMethods {
+			definition, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			order, _ := definition[OrderExtension].(float64)
+			if intOrder, ok := definition[OrderExtension].(int); ok {
+				order = float64(intOrder)
+			}
+
+			operations = append(operations, SpecOperation{
+				Path:        pathName,
+				Method:      method,
+				SerialGroup: serialGroup(definition[SerialExtension]),
+				Order:       order,
+				Definition:  definition,
+			})
+		}
+	}
+
+	return operations
+}
+
+// x-signet-serial can be true, or the name of a group of operations
+func serialGroup(extension interface{}) string {
+	switch value := extension.(type) {
+	case bool:
+		if value {
+			return "serial"
+		}
+	case string:
+		return value
+	}
+	return ""
+}
+
+/*
+BatchSpecOperations groups operations into batches that can be verified in
+parallel. Operations in the same serial group share a batch, sorted by
+x-signet-order, and every other operation gets its own. Batches are ordered
+by their first operation in path and method order.
+*/
+func BatchSpecOperations(operations []SpecOperation) [][]SpecOperation {
+	batches := [][]SpecOperation{}
+	groupIndexes := map[string]int{}
+
+	for _, op := range operations {
+		if len(op.SerialGroup) == 0 {
+			batches = append(batches, []SpecOperation{op})
+			continue
+		}
+
+		i, ok := groupIndexes[op.SerialGroup]
+		if !ok {
+			groupIndexes[op.SerialGroup] = len(batches)
+			batches = append(batches, []SpecOperation{op})
+			continue
+		}
+
+		batches[i] = append(batches[i], op)
+	}
+
+	for _, batch := range batches {
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].Order < batch[j].Order })
+	}
+
+	return batches
+}
+
+/*
+SpecForOperations returns a copy of the spec that only has the given
+operations. Everything outside of paths (ex. components) is kept, so that
+references still resolve, as are path level parameters.
+*/
+func SpecForOperations(spec map[string]interface{}, operations []SpecOperation) map[string]interface{} {
+	paths, _ := spec["paths"].(map[string]interface{})
+	keptPaths := map[string]interface{}{}
+
+	for _, op := range operations {
+		keptItem, ok := keptPaths[op.Path].(map[string]interface{})
+		if !ok {
+			keptItem = map[string]interface{}{}
+			pathItem, _ := paths[op.Path].(map[string]interface{})
+			for key, value := range pathItem {
+				if !containsMethod(key) {
+					keptItem[key] = value
+				}
+			}
+			keptPaths[op.Path] = keptItem
+		}
+
+		keptItem[op.Method] = op.Definition
+	}
+
+	specCopy := map[string]interface{}{}
+	for key, value := range spec {
+		specCopy[key] = value
+	}
+	specCopy["paths"] = keptPaths
+
+	return specCopy
+}
+
+func containsMethod(key string) bool {
+	for _, method := range specMethods {
+		if key == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateParameter is the definition of a parameter in a CloudFormation template
+type TemplateParameter struct {
+	Type           string        `yaml:"Type"`
+	Default        interface{}   `yaml:"Default"`
+	AllowedValues  []interface{} `yaml:"AllowedValues"`
+	AllowedPattern string        `yaml:"AllowedPattern"`
+	MinValue       *float64      `yaml:"MinValue"`
+	MaxValue       *float64      `yaml:"MaxValue"`
+	MinLength      *int          `yaml:"MinLength"`
+	MaxLength      *int          `yaml:"MaxLength"`
+	Description    string        `yaml:"Description"`
+}
+
+// DefaultString is the default value of the parameter as CloudFormation would read it
+func (p TemplateParameter) DefaultString() string {
+	return parameterValueString(p.Default)
+}
+
+// LoadTemplateParameters reads the parameters section of a CloudFormation template
+func LoadTemplateParameters(template string) (map[string]TemplateParameter, error) {
+	var parsed struct {
+		Parameters map[string]TemplateParameter `yaml:"Parameters"`
+	}
+
+	err := yaml.Unmarshal([]byte(template), &parsed)
+	if err != nil {
+		return nil, errors.New("unable to read the parameters of the CloudFormation template: " + err.Error())
+	}
+
+	if parsed.Parameters == nil {
+		parsed.Parameters = map[string]TemplateParameter{}
+	}
+	return parsed.Parameters, nil
+}
+
+/*
+LoadStackParametersFile reads a JSON or YAML parameters file. It can either be
+a map of parameter names to values, or the list of ParameterKey and
+ParameterValue objects used by the AWS CLI. Lists of values (ex. subnet IDs)
+are joined with commas.
+*/
+func LoadStackParametersFile(path string) (map[string]string, error) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("unable to read parameters file: " + err.Error())
+	}
+
+	var parsed interface{}
+	err = yaml.Unmarshal(fileBytes, &parsed)
+	if err != nil {
+		return nil, errors.New("unable to parse parameters file " + path + ": " + err.Error())
+	}
+
+	params := map[string]string{}
+
+	switch typed := parsed.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range typed {
+			params[fmt.Sprint(key)] = parameterValueString(value)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			param, ok := item.(map[interface{}]interface{})
+			if !ok || param["ParameterKey"] == nil {
+				return nil, errors.New("parameters file " + path + " must be a map of parameter names to values, or a list of ParameterKey and ParameterValue objects")
+			}
+			params[fmt.Sprint(param["ParameterKey"])] = parameterValueString(param["ParameterValue"])
+		}
+	case nil:
+	default:
+		return nil, errors.New("parameters file " + path + " must be a map of parameter names to values, or a list of ParameterKey and ParameterValue objects")
+	}
+
+	return params, nil
+}
+
+func parameterValueString(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := []string{}
+		for _, item := range typed {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ",")
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+/*
+ValidateStackParameters checks parameter values against their definitions in
+the template, the same way CloudFormation would, so that mistakes are found
+before a stack is created. Every problem is returned, sorted by parameter.
+*/
+func ValidateStackParameters(params map[string]string, templateParams map[string]TemplateParameter) []string {
+	problems := []string{}
+
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		definition, ok := templateParams[name]
+		if !ok {
+			problems = append(problems, name+" is not a parameter of the template")
+			continue
+		}
+
+		problems = append(problems, validateParameterValue(name, params[name], definition)...)
+	}
+
+	for _, name := range sortedParameterNames(templateParams) {
+		if _, ok := params[name]; !ok && templateParams[name].Default == nil {
+			problems = append(problems, name+" is required by the template")
+		}
+	}
+
+	return problems
+}
+
+func validateParameterValue(name, value string, definition TemplateParameter) []string {
+	problems := []string{}
+
+	values := []string{value}
+	if strings.HasPrefix(definition.Type, "List<") || definition.Type == "CommaDelimitedList" {
+		values = strings.Split(value, ",")
+	}
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+
+		if definition.Type == "Number" || definition.Type == "List<Number>" {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				problems = append(problems, name+" must be a number, got "+strconv.Quote(value))
+				continue
+			}
+			if definition.MinValue != nil && number < *definition.MinValue {
+				problems = append(problems, name+" must be at least "+parameterValueString(*definition.MinValue)+", got "+value)
+			}
+			if definition.MaxValue != nil && number > *definition.MaxValue {
+				problems = append(problems, name+" must be at most "+parameterValueString(*definition.MaxValue)+", got "+value)
+			}
+		}
+
+		if definition.MinLength != nil && len(value) < *definition.MinLength {
+			problems = append(problems, name+" must be at least "+strconv.Itoa(*definition.MinLength)+" characters")
+		}
+		if definition.MaxLength != nil && len(value) > *definition.MaxLength {
+			problems = append(problems, name+" must be at most "+strconv.Itoa(*definition.MaxLength)+" characters")
+		}
+
+		if len(definition.AllowedPattern) != 0 {
+			pattern, err := regexp.Compile(definition.AllowedPattern)
+			if err == nil && !pattern.MatchString(value) {
+				problems = append(problems, name+" must match "+definition.AllowedPattern+", got "+strconv.Quote(value))
+			}
+		}
+
+		if len(definition.AllowedValues) != 0 && !allowedParameterValue(value, definition.AllowedValues) {
+			allowed := []string{}
+			for _, allowedValue := range definition.AllowedValues {
+				allowed = append(allowed, fmt.Sprint(allowedValue))
+			}
+			problems = append(problems, name+" must be one of "+strings.Join(allowed, ", ")+", got "+strconv.Quote(value))
+		}
+	}
+
+	return problems
+}
+
+func allowedParameterValue(value string, allowedValues []interface{}) bool {
+	for _, allowedValue := range allowedValues {
+		if fmt.Sprint(allowedValue) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedParameterNames(templateParams map[string]TemplateParameter) []string {
+	names := []string{}
+	for name := range templateParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// the memory (MiB) Fargate allows for each CPU size
+var fargateMemory = map[int][]int{
+	256:  {512, 1024, 2048},
+	512:  {1024, 2048, 3072, 4096},
+	1024: fargateMemoryRange(2048, 8192),
+	2048: fargateMemoryRange(4096, 16384),
+	4096: fargateMemoryRange(8192, 30720),
+}
+
+func fargateMemoryRange(min, max int) []int {
+	memory := []int{}
+	for value := min; value <= max; value += 1024 {
+		memory = append(memory, value)
+	}
+	return memory
+}
+
+// ValidateFargateSize checks that the task memory is allowed for the task CPU on Fargate
+func ValidateFargateSize(cpu, memory string) error {
+	cpuInt, err := strconv.Atoi(cpu)
+	if err != nil {
+		return nil
+	}
+	memoryInt, err := strconv.Atoi(memory)
+	if err != nil {
+		return nil
+	}
+
+	allowed, ok := fargateMemory[cpuInt]
+	if !ok {
+		return nil
+	}
+
+	for _, value := range allowed {
+		if value == memoryInt {
+			return nil
+		}
+	}
+
+	return errors.New("TaskMemory " + memory + " is not valid for TaskCpu " + cpu + " on Fargate, it must be between " + strconv.Itoa(allowed[0]) + " and " + strconv.Itoa(allowed[len(allowed)-1]) + " MiB")
+}
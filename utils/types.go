@@ -38,7 +38,7 @@ type DeploymentBody struct {
 }
 
 type MbProxy struct {
-	To string `json:"to"`
+	To   string `json:"to"`
 	Mode string `json:"mode"`
 }
 
@@ -47,12 +47,15 @@ type MbResponse struct {
 }
 
 type MbStub struct {
-	Responses []MbResponse `json:"responses"`
+	Predicates []map[string]interface{} `json:"predicates,omitempty"`
+	Responses  []MbResponse             `json:"responses"`
 }
 
 type ProxyConfig struct {
-	Port     int          `json:"port"`
-	Name     string       `json:"name"`
-	Protocol string       `json:"protocol"`
+	Port     int      `json:"port"`
+	Name     string   `json:"name"`
+	Protocol string   `json:"protocol"`
+	Key      string   `json:"key,omitempty"`
+	Cert     string   `json:"cert,omitempty"`
 	Stubs    []MbStub `json:"stubs"`
-}
\ No newline at end of file
+}